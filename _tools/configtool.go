@@ -0,0 +1,310 @@
+///usr/bin/env go run "$0" "$@"; exit
+// +build tool
+
+/*
+ * configtool.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/billziss-gh/golib/cmd"
+	"github.com/billziss-gh/golib/config"
+	"github.com/billziss-gh/golib/util"
+)
+
+var dialects = map[string]*config.Dialect{
+	"ini": config.DefaultDialect,
+	"git": &config.Dialect{
+		AssignChars:           "=",
+		CommentChars:          "#;",
+		ReadEmptyKeys:         true,
+		WriteEmptyKeys:        true,
+		BackslashContinuation: true,
+		Subsections:           true,
+	},
+}
+
+var formats = []string{"ini", "git", "json", "toml", "env"}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
+
+func usage(c *cmd.Cmd) {
+	c.Flag.Usage()
+	os.Exit(2)
+}
+
+func getDialect(c *cmd.Cmd) *config.Dialect {
+	name := c.GetFlag("d").(string)
+	dialect, ok := dialects[name]
+	if !ok {
+		fail(fmt.Errorf("unknown dialect %s", name))
+	}
+	return dialect
+}
+
+// format determines the format of a file from a flag or the file extension.
+func format(name string, path string) string {
+	if "" != name {
+		for _, f := range formats {
+			if f == name {
+				return name
+			}
+		}
+		fail(fmt.Errorf("unknown format %s", name))
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".toml":
+		return "toml"
+	case ".env":
+		return "env"
+	}
+	return "ini"
+}
+
+func check(c *cmd.Cmd, args []string) {
+	c.Flag.Parse(args)
+	if 0 == c.Flag.NArg() {
+		usage(c)
+	}
+
+	dialect := getDialect(c)
+	problems := 0
+	for _, path := range c.Flag.Args() {
+		file, err := os.Open(path)
+		if nil != err {
+			fail(err)
+		}
+
+		report := func(tok config.Token, msg string, args ...interface{}) {
+			fmt.Printf("%s:%d:%d: %s\n", path, tok.Line, tok.Column, fmt.Sprintf(msg, args...))
+			problems++
+		}
+
+		sects := map[string]int{}
+		props := map[string]int{}
+		scanner := dialect.NewScanner(file)
+		for scanner.Scan() {
+			tok := scanner.Token()
+			switch tok.Kind {
+			case config.TokenError:
+				report(tok, "%s", tok.Reason)
+			case config.TokenSectionStart:
+				if l, ok := sects[tok.Section]; ok {
+					report(tok, "duplicate section [%s] (first at line %d)", tok.Section, l)
+				} else {
+					sects[tok.Section] = tok.Line
+				}
+			case config.TokenProperty:
+				if dialect.Lists && strings.HasSuffix(tok.Name, "[]") {
+					continue
+				}
+				k := tok.Name
+				if "" != tok.Section {
+					k = tok.Section + "." + tok.Name
+				}
+				if l, ok := props[k]; ok {
+					report(tok, "duplicate property %s (first at line %d)", k, l)
+				} else {
+					props[k] = tok.Line
+				}
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if nil != err {
+			fail(err)
+		}
+	}
+
+	if 0 != problems {
+		os.Exit(1)
+	}
+}
+
+func fmtfile(dialect *config.Dialect, path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	scanner := dialect.NewScanner(file)
+	for scanner.Scan() {
+		tok := scanner.Token()
+		if config.TokenError == tok.Kind {
+			return nil, fmt.Errorf("%s:%d:%d: %s", path, tok.Line, tok.Column, tok.Reason)
+		}
+		buf.WriteString(dialect.FormatToken(tok))
+		buf.WriteByte('\n')
+	}
+	err = scanner.Err()
+	if nil != err {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func fmtcmd(c *cmd.Cmd, args []string) {
+	c.Flag.Parse(args)
+	if 0 == c.Flag.NArg() {
+		usage(c)
+	}
+
+	dialect := getDialect(c)
+	write := c.GetFlag("w").(bool)
+	for _, path := range c.Flag.Args() {
+		data, err := fmtfile(dialect, path)
+		if nil != err {
+			fail(err)
+		}
+		if !write {
+			os.Stdout.Write(data)
+			continue
+		}
+		info, err := os.Stat(path)
+		if nil != err {
+			fail(err)
+		}
+		err = util.WriteData(path, info.Mode().Perm(), data)
+		if nil != err {
+			fail(err)
+		}
+	}
+}
+
+func convert(c *cmd.Cmd, args []string) {
+	c.Flag.Parse(args)
+	if 2 < c.Flag.NArg() {
+		usage(c)
+	}
+
+	inpath, outpath := c.Flag.Arg(0), c.Flag.Arg(1)
+	prefix := c.GetFlag("prefix").(string)
+
+	var reader io.Reader = os.Stdin
+	if "" != inpath && "-" != inpath {
+		file, err := os.Open(inpath)
+		if nil != err {
+			fail(err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var conf config.TypedConfig
+	var err error
+	switch f := format(c.GetFlag("from").(string), inpath); f {
+	case "json":
+		conf, err = config.ReadJSON(reader)
+	case "toml":
+		conf, err = config.ReadTOML(reader)
+	case "env":
+		conf, err = config.ReadEnv(reader, prefix, nil)
+	default:
+		conf, err = dialects[f].ReadTyped(reader)
+	}
+	if nil != err {
+		fail(err)
+	}
+
+	var buf bytes.Buffer
+	switch f := format(c.GetFlag("to").(string), outpath); f {
+	case "json":
+		err = config.WriteJSON(&buf, conf)
+	case "toml":
+		err = config.WriteTOML(&buf, conf)
+	case "env":
+		err = config.WriteEnv(&buf, conf, prefix)
+	default:
+		err = dialects[f].WriteTyped(&buf, conf)
+	}
+	if nil != err {
+		fail(err)
+	}
+
+	if "" == outpath || "-" == outpath {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	err = util.WriteData(outpath, 0644, buf.Bytes())
+	if nil != err {
+		fail(err)
+	}
+}
+
+func get(c *cmd.Cmd, args []string) {
+	c.Flag.Parse(args)
+	if 2 != c.Flag.NArg() {
+		usage(c)
+	}
+
+	conf, err := getDialect(c).ReadFile(c.Flag.Arg(0))
+	if nil != err {
+		fail(err)
+	}
+
+	name := c.Flag.Arg(1)
+	valu, ok, _ := conf.LookupString(name)
+	if !ok {
+		isset := false
+		c.Flag.Visit(func(f *flag.Flag) {
+			isset = isset || "default" == f.Name
+		})
+		if !isset {
+			fmt.Fprintf(os.Stderr, "error: property %s not found\n", name)
+			os.Exit(1)
+		}
+		valu = c.GetFlag("default").(string)
+	}
+	fmt.Println(valu)
+}
+
+func init() {
+	c := cmd.Add("check [-d dialect] file...\ncheck files for syntax errors and duplicates", check)
+	c.Flag.String("d", "ini", "`dialect` (ini, git)")
+
+	c = cmd.Add("fmt [-d dialect] [-w] file...\nnormalize quoting and assignment characters", fmtcmd)
+	c.Flag.String("d", "ini", "`dialect` (ini, git)")
+	c.Flag.Bool("w", false, "write result to file instead of stdout")
+
+	c = cmd.Add("convert [-from format] [-to format] [-prefix prefix] [infile [outfile]]\n"+
+		"convert between formats (ini, git, json, toml, env)", convert)
+	c.Flag.String("from", "", "input `format` (default: from file extension or ini)")
+	c.Flag.String("to", "", "output `format` (default: from file extension or ini)")
+	c.Flag.String("prefix", "", "variable name `prefix` for env files")
+
+	c = cmd.Add("get [-d dialect] [-default value] file SECTION.PROPNAME\n"+
+		"print a property value", get)
+	c.Flag.String("d", "ini", "`dialect` (ini, git)")
+	c.Flag.String("default", "", "`value` printed when the property is missing")
+}
+
+func main() {
+	flag.Usage = cmd.UsageFunc()
+	flag.Parse()
+	cmd.Run()
+}
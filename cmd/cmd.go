@@ -17,16 +17,63 @@ package cmd
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// Errors returned by RunE carry one of the following attachments
+// (see errors.HasAttachment).
+const (
+	// ErrUnknownCommand reports a missing or unknown command.
+	ErrUnknownCommand = "ErrUnknownCommand"
+
+	// ErrHelp reports that help was requested with the help command
+	// or the -h/-help flags.
+	ErrHelp = "ErrHelp"
+
+	// ErrFlag reports a flag parsing failure.
+	ErrFlag = "ErrFlag"
 )
 
+// ExitCodes maps error attachments to program exit codes. It is used by
+// ExitCode and may be changed by programs at the top level.
+var ExitCodes = map[interface{}]int{
+	ErrUnknownCommand: 2,
+	ErrHelp:           2,
+	ErrFlag:           2,
+}
+
+// ExitCode returns the program exit code for an error returned by RunE.
+// It returns 0 for a nil error, the code found in ExitCodes for the first
+// matching attachment in the causal chain of the error, and 1 otherwise.
+func ExitCode(err error) int {
+	if nil == err {
+		return 0
+	}
+	for e := err; nil != e; e = errors.Cause(e) {
+		a := errors.Attachment(e)
+		if nil != a && !reflect.TypeOf(a).Comparable() {
+			// not usable as a map key
+			continue
+		}
+		if code, ok := ExitCodes[a]; ok {
+			return code
+		}
+	}
+	return 1
+}
+
 // CmdMap encapsulates a (sub-)command map.
 type CmdMap struct {
 	cmdmap map[string]*Cmd
 	cmdlst []string
+	parent *Cmd
 	mux    sync.Mutex
 }
 
@@ -36,13 +83,40 @@ type Cmd struct {
 	Flag *flag.FlagSet
 
 	// Main is the function to run when the command is selected.
+	// Main may be nil for commands that only group sub-commands.
 	Main func(cmd *Cmd, args []string)
 
+	// MainE is the error returning variant of Main (see AddE).
+	MainE func(cmd *Cmd, args []string) error
+
+	// Complete returns dynamic completions for the command (see
+	// CmdMap.Completions). It receives the arguments that follow the
+	// command name and the word being completed.
+	Complete func(cmd *Cmd, args []string, word string) []string
+
 	// Use contains the command usage string.
 	Use string
 
 	// Desc contains the command description.
 	Desc string
+
+	// Sub contains the sub-commands of the command (if any).
+	Sub *CmdMap
+
+	// Aliases contains additional names for the command.
+	Aliases []string
+
+	// Hidden excludes the command from help output and completions.
+	// A hidden command can still be run using its full name or aliases.
+	Hidden bool
+
+	// Deprecated marks the command as deprecated. It contains a message
+	// such as the replacement command (e.g. "use remote add instead"),
+	// which is printed as a warning when the command is run.
+	Deprecated string
+
+	name   string
+	parent *Cmd
 }
 
 // Add adds a new command in the command map.
@@ -55,7 +129,29 @@ type Cmd struct {
 //
 // Then the command name becomes "NAME", the command Use field becomes
 // "NAME ARGUMENTS" and the command Desc field becomes "DESCRIPTION".
+//
+// The command name may be a dotted path such as "remote.add". If the
+// command map contains a command named "remote" the new command "add" is
+// added as a sub-command of "remote" (in its Sub command map, which is
+// created if necessary). In all cases the dots in the name are replaced
+// by spaces in the Use field.
 func (self *CmdMap) Add(name string, main func(*Cmd, []string)) (cmd *Cmd) {
+	cmd = self.add(name, flag.ExitOnError)
+	cmd.Main = main
+	return
+}
+
+// AddE adds a new command in the command map. The command main function
+// returns an error and the command flag set is created with
+// flag.ContinueOnError so that it is suitable for use with RunE.
+// The name parameter is interpreted as in Add.
+func (self *CmdMap) AddE(name string, main func(*Cmd, []string) error) (cmd *Cmd) {
+	cmd = self.add(name, flag.ContinueOnError)
+	cmd.MainE = main
+	return
+}
+
+func (self *CmdMap) add(name string, errorHandling flag.ErrorHandling) (cmd *Cmd) {
 	lines := strings.SplitN(name, "\n", 2)
 	use := lines[0]
 	desc := ""
@@ -63,28 +159,91 @@ func (self *CmdMap) Add(name string, main func(*Cmd, []string)) (cmd *Cmd) {
 		desc = lines[1]
 	}
 	parts := strings.SplitN(use, " ", 2)
-	parts[0] = strings.Replace(parts[0], ".", " ", -1)
-	name = parts[0]
-	if i := strings.LastIndex(parts[0], " "); -1 != i {
-		name = name[i+1:]
+	path := strings.Split(parts[0], ".")
+
+	cmdmap := self
+	for 1 < len(path) {
+		parent := cmdmap.Get(path[0])
+		if nil == parent {
+			break
+		}
+		cmdmap = parent.subCmdMap()
+		path = path[1:]
+	}
+
+	name = path[len(path)-1]
+	parts[0] = strings.Join(path, " ")
+	if nil != cmdmap.parent {
+		parts[0] = cmdmap.parent.Path() + " " + parts[0]
 	}
 	use = strings.Join(parts, " ")
-	cmd = &Cmd{Flag: flag.NewFlagSet(name, flag.ExitOnError), Main: main, Use: use, Desc: desc}
+	cmd = &Cmd{Flag: flag.NewFlagSet(name, errorHandling), Use: use, Desc: desc,
+		name: name, parent: cmdmap.parent}
 	cmd.Flag.Usage = UsageFunc(cmd)
 
-	self.mux.Lock()
-	defer self.mux.Unlock()
-	self.cmdmap[name] = cmd
-	self.cmdlst = append(self.cmdlst, name)
+	cmdmap.mux.Lock()
+	defer cmdmap.mux.Unlock()
+	cmdmap.cmdmap[name] = cmd
+	cmdmap.cmdlst = append(cmdmap.cmdlst, name)
 
 	return
 }
 
-// Get gets a command by name.
+// Get gets a command by name or alias.
 func (self *CmdMap) Get(name string) *Cmd {
 	self.mux.Lock()
 	defer self.mux.Unlock()
-	return self.cmdmap[name]
+	if cmd, ok := self.cmdmap[name]; ok {
+		return cmd
+	}
+	for _, n := range self.cmdlst {
+		cmd := self.cmdmap[n]
+		for _, alias := range cmd.Aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// Find finds a command by name, alias or unambiguous prefix of a name or
+// alias. Hidden commands are only found by their full name or aliases.
+// Find returns an error with the ErrUnknownCommand attachment if there is
+// no such command or if the prefix is ambiguous.
+func (self *CmdMap) Find(name string) (*Cmd, error) {
+	if cmd := self.Get(name); nil != cmd {
+		return cmd, nil
+	}
+
+	var cmds []*Cmd
+	var names []string
+	if "" != name {
+		for _, n := range self.GetNames() {
+			cmd := self.Get(n)
+			if nil == cmd || cmd.Hidden {
+				continue
+			}
+			for _, alias := range append([]string{n}, cmd.Aliases...) {
+				if strings.HasPrefix(alias, name) {
+					cmds = append(cmds, cmd)
+					names = append(names, alias)
+					break
+				}
+			}
+		}
+	}
+
+	switch len(cmds) {
+	case 0:
+		return nil, errors.New("unknown command "+name, nil, ErrUnknownCommand)
+	case 1:
+		return cmds[0], nil
+	default:
+		return nil, errors.New(
+			fmt.Sprintf("ambiguous command %s (%s)", name, strings.Join(names, ", ")),
+			nil, ErrUnknownCommand)
+	}
 }
 
 // GetNames gets all command names.
@@ -96,50 +255,218 @@ func (self *CmdMap) GetNames() []string {
 	return cmdlst
 }
 
-// PrintCmds prints help text for all commands to stderr.
-func (self *CmdMap) PrintCmds() {
+// visibleNames gets the names of all commands that are not hidden.
+func (self *CmdMap) visibleNames() []string {
+	var names []string
 	for _, name := range self.GetNames() {
+		if cmd := self.Get(name); nil != cmd && !cmd.Hidden {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// PrintCmds prints help text for all commands to stderr. Hidden commands
+// are omitted.
+func (self *CmdMap) PrintCmds() {
+	for _, name := range self.visibleNames() {
 		cmd := self.Get(name)
 		if nil == cmd {
 			continue
 		}
-		fmt.Fprintln(os.Stderr, "  "+name)
-		if "" != cmd.Desc {
-			fmt.Fprintln(os.Stderr, "    \t"+cmd.Desc)
+		fmt.Fprintln(os.Stderr, "  "+strings.Join(append([]string{name}, cmd.Aliases...), ", "))
+		desc := cmd.Desc
+		if "" != cmd.Deprecated {
+			desc = strings.TrimSpace(desc + " (deprecated: " + cmd.Deprecated + ")")
+		}
+		if "" != desc {
+			fmt.Fprintln(os.Stderr, "    \t"+desc)
 		}
 	}
 }
 
 // Run parses the command line and executes the specified (sub-)command.
+// Run exits the program if the command cannot be run or if it returns
+// an error; the exit code is determined by ExitCode.
 func (self *CmdMap) Run(flagSet *flag.FlagSet, args []string) {
+	err := self.RunE(flagSet, args)
+	if nil != err {
+		exit(err)
+	}
+}
+
+// RunE parses the command line and executes the specified (sub-)command.
+// RunE returns an error rather than exiting the program: errors with the
+// ErrUnknownCommand, ErrHelp and ErrFlag attachments are returned after
+// the relevant usage text has been printed; errors from a command MainE
+// function are returned as is.
+//
+// If the command has sub-commands RunE parses the command flags and
+// dispatches to the sub-command named by the next argument (see Find).
+// If there is no such sub-command the command main function (if any) is
+// run with the command arguments unparsed, so that it parses the command
+// flags itself as usual.
+func (self *CmdMap) RunE(flagSet *flag.FlagSet, args []string) error {
 	if !flagSet.Parsed() {
-		flagSet.Parse(args)
+		err := parse(flagSet, args)
+		if nil != err {
+			return err
+		}
 	}
 
 	arg := flagSet.Arg(0)
 	cmd := self.Get(arg)
 
 	if nil == cmd {
+		if completeCmd == arg {
+			self.complete(flagSet, flagSet.Args()[1:])
+			return nil
+		}
 		if "help" == arg {
-			args = flagSet.Args()[1:]
-			if 0 == len(args) {
-				flagSet.Usage()
-			} else {
-				for _, name := range args {
-					cmd := self.Get(name)
-					if nil == cmd {
-						continue
-					}
-					cmd.Flag.Usage()
-				}
-			}
-		} else {
+			self.help(flagSet, flagSet.Args()[1:])
+			return errors.New("help requested", nil, ErrHelp)
+		}
+		if "" == arg {
+			flagSet.Usage()
+			return errors.New("missing command", nil, ErrUnknownCommand)
+		}
+		var err error
+		cmd, err = self.Find(arg)
+		if nil != err {
+			fmt.Fprintln(os.Stderr, err)
 			flagSet.Usage()
+			return err
 		}
-		os.Exit(2)
 	}
 
-	cmd.Main(cmd, flagSet.Args()[1:])
+	return cmd.run(flagSet.Args()[1:])
+}
+
+func (self *CmdMap) help(flagSet *flag.FlagSet, args []string) {
+	if 0 == len(args) {
+		flagSet.Usage()
+		return
+	}
+
+	// help for a nested command: help a b
+	if cmd := self.lookup(args); nil != cmd {
+		cmd.Flag.Usage()
+		return
+	}
+
+	for _, name := range args {
+		cmd, _ := self.Find(name)
+		if nil == cmd {
+			continue
+		}
+		cmd.Flag.Usage()
+	}
+}
+
+// lookup finds a command by its path of names.
+func (self *CmdMap) lookup(path []string) *Cmd {
+	cmd, _ := self.Find(path[0])
+	for _, name := range path[1:] {
+		if nil == cmd || nil == cmd.Sub {
+			return nil
+		}
+		cmd, _ = cmd.Sub.Find(name)
+	}
+	return cmd
+}
+
+func (self *Cmd) run(args []string) error {
+	if "" != self.Deprecated {
+		fmt.Fprintf(os.Stderr, "warning: command %s is deprecated: %s\n",
+			self.Path(), self.Deprecated)
+	}
+
+	if nil != self.Sub {
+		hasMain := nil != self.Main || nil != self.MainE
+		arg := self.peekArg(args)
+		if sub, _ := self.Sub.Find(arg); !hasMain || "help" == arg || nil != sub {
+			err := self.Parse(args)
+			if nil != err {
+				return err
+			}
+			return self.Sub.RunE(self.Flag, self.Flag.Args())
+		}
+	}
+
+	switch {
+	case nil != self.MainE:
+		return self.MainE(self, args)
+	case nil != self.Main:
+		self.Main(self, args)
+		return nil
+	}
+
+	self.Flag.Usage()
+	return errors.New("missing command", nil, ErrUnknownCommand)
+}
+
+// probeValue is used by peekArg to skip flags without setting them.
+type probeValue bool
+
+func (v probeValue) String() string {
+	return ""
+}
+
+func (v probeValue) Set(string) error {
+	return nil
+}
+
+func (v probeValue) IsBoolFlag() bool {
+	return bool(v)
+}
+
+// peekArg gets the first argument that follows the command flags without
+// setting the flags. It returns "" if the flags cannot be parsed.
+func (self *Cmd) peekArg(args []string) string {
+	flagSet := flag.NewFlagSet(self.Flag.Name(), flag.ContinueOnError)
+	flagSet.SetOutput(ioutil.Discard)
+	flagSet.Usage = func() {}
+	self.Flag.VisitAll(func(f *flag.Flag) {
+		flagSet.Var(probeValue(isBoolFlag(f)), f.Name, "")
+	})
+	if nil != flagSet.Parse(args) {
+		return ""
+	}
+	return flagSet.Arg(0)
+}
+
+// Parse parses the command flags. Unlike flag.FlagSet.Parse the returned
+// errors carry the ErrHelp or ErrFlag attachments.
+func (self *Cmd) Parse(args []string) error {
+	return parse(self.Flag, args)
+}
+
+func parse(flagSet *flag.FlagSet, args []string) error {
+	err := flagSet.Parse(args)
+	if nil == err {
+		return nil
+	}
+	if flag.ErrHelp == err {
+		return errors.New("help requested", err, ErrHelp)
+	}
+	return errors.New("invalid flags", err, ErrFlag)
+}
+
+// printError prints an error to stderr unless it is one of the errors with
+// ErrUnknownCommand, ErrHelp or ErrFlag attachments (whose usage text has
+// already been printed).
+func printError(err error) {
+	if !errors.HasAttachment(err, ErrUnknownCommand) &&
+		!errors.HasAttachment(err, ErrHelp) &&
+		!errors.HasAttachment(err, ErrFlag) {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+}
+
+// exit prints an error and exits the program with the exit code of the error.
+func exit(err error) {
+	printError(err)
+	os.Exit(ExitCode(err))
 }
 
 // NewCmdMap creates a new command map.
@@ -149,16 +476,45 @@ func NewCmdMap() *CmdMap {
 	}
 }
 
-// GetFlag gets the value of the named flag.
+// GetFlag gets the value of the named flag. If the command does not have
+// the named flag, the flags of its parent commands are searched.
 func (self *Cmd) GetFlag(name string) interface{} {
-	if f := self.Flag.Lookup(name); nil != f {
-		if g, ok := f.Value.(flag.Getter); ok {
-			return g.Get()
+	for cmd := self; nil != cmd; cmd = cmd.parent {
+		if f := cmd.Flag.Lookup(name); nil != f {
+			if g, ok := f.Value.(flag.Getter); ok {
+				return g.Get()
+			}
+			return nil
 		}
 	}
 	return nil
 }
 
+// Parent gets the parent command of a sub-command.
+func (self *Cmd) Parent() *Cmd {
+	return self.parent
+}
+
+// Path gets the names of the command and its parent commands separated
+// by spaces (e.g. "remote add").
+func (self *Cmd) Path() string {
+	if nil == self.parent {
+		return self.name
+	}
+	return self.parent.Path() + " " + self.name
+}
+
+// subCmdMap gets the sub-command map of a command, creating it if necessary.
+func (self *Cmd) subCmdMap() *CmdMap {
+	if nil == self.Sub {
+		self.Sub = NewCmdMap()
+	}
+	if nil == self.Sub.parent {
+		self.Sub.parent = self
+	}
+	return self.Sub
+}
+
 // DefaultCmdMap is the default command map.
 var DefaultCmdMap = NewCmdMap()
 
@@ -172,10 +528,17 @@ var DefaultCmdMap = NewCmdMap()
 //
 // Then the command name becomes "NAME", the command Use field becomes
 // "NAME ARGUMENTS" and the command Desc field becomes "DESCRIPTION".
+// A dotted name adds a sub-command as described in CmdMap.Add.
 func Add(name string, main func(*Cmd, []string)) *Cmd {
 	return DefaultCmdMap.Add(name, main)
 }
 
+// AddE adds a new command with an error returning main function in the
+// default command map. See CmdMap.AddE.
+func AddE(name string, main func(*Cmd, []string) error) *Cmd {
+	return DefaultCmdMap.AddE(name, main)
+}
+
 // PrintCmds prints help text for all commands in the default command map
 // to stderr.
 func PrintCmds() {
@@ -188,10 +551,18 @@ func Run() {
 	DefaultCmdMap.Run(flag.CommandLine, os.Args[1:])
 }
 
+// RunE parses the command line and executes the specified (sub-)command
+// from the default command map. See CmdMap.RunE.
+func RunE() error {
+	return DefaultCmdMap.RunE(flag.CommandLine, os.Args[1:])
+}
+
 // UsageFunc returns a usage function appropriate for use with flag.FlagSet.
 func UsageFunc(args ...interface{}) func() {
 	var (
 		cmdmap  *CmdMap
+		cmd     *Cmd
+		cmdFlag bool
 		use     string
 		flagSet *flag.FlagSet
 	)
@@ -203,6 +574,8 @@ func UsageFunc(args ...interface{}) func() {
 		for _, arg := range args {
 			switch a := arg.(type) {
 			case *Cmd:
+				cmd = a
+				cmdFlag = true
 				use = a.Use
 				flagSet = a.Flag
 			case *CmdMap:
@@ -210,16 +583,30 @@ func UsageFunc(args ...interface{}) func() {
 			case string:
 				use = a
 			case *flag.FlagSet:
+				cmdFlag = false
 				flagSet = a
 			}
 		}
 	}
 
 	return func() {
+		cmdmap := cmdmap
+		if nil == cmdmap && nil != cmd && nil != cmd.Sub {
+			// sub-commands may be added after the usage function is created
+			cmdmap = cmd.Sub
+		}
+
+		flagSet := flagSet
+		if cmdFlag {
+			// the command flag set may be replaced (e.g. by Repl)
+			flagSet = cmd.Flag
+		}
+
+		use := use
 		progname := filepath.Base(os.Args[0])
 		cmdCount := 0
 		if nil != cmdmap {
-			cmdCount = len(cmdmap.GetNames())
+			cmdCount = len(cmdmap.visibleNames())
 		}
 
 		flagCount := 0
@@ -229,37 +616,42 @@ func UsageFunc(args ...interface{}) func() {
 			})
 		}
 
-		switch {
-		case 0 == cmdCount && 0 == flagCount:
-			if "" == use {
-				fmt.Fprintf(os.Stderr, "usage: %s\n", progname)
-			} else {
-				fmt.Fprintf(os.Stderr, "usage: %s %s\n", progname, use)
-			}
-		case 0 != cmdCount && 0 == flagCount:
-			if "" == use {
+		if "" == use {
+			switch {
+			case 0 != cmdCount && 0 == flagCount:
 				use = "command args..."
-			}
-			fmt.Fprintf(os.Stderr, "usage: %s %s\n", progname, use)
-			fmt.Fprintln(os.Stderr)
-			fmt.Fprintln(os.Stderr, "commands:")
-			cmdmap.PrintCmds()
-		case 0 == cmdCount && 0 != flagCount:
-			if "" == use {
+			case 0 == cmdCount && 0 != flagCount:
 				use = "[-options] args..."
-			}
-			fmt.Fprintf(os.Stderr, "usage: %s %s\n", progname, use)
-			flagSet.PrintDefaults()
-		default:
-			if "" == use {
+			case 0 != cmdCount && 0 != flagCount:
 				use = "[-options] command args..."
 			}
+		}
+		if "" == use {
+			fmt.Fprintf(os.Stderr, "usage: %s\n", progname)
+		} else {
 			fmt.Fprintf(os.Stderr, "usage: %s %s\n", progname, use)
+		}
+
+		if nil != cmd {
+			if 0 < len(cmd.Aliases) {
+				fmt.Fprintf(os.Stderr, "aliases: %s\n", strings.Join(cmd.Aliases, ", "))
+			}
+			if "" != cmd.Deprecated {
+				fmt.Fprintf(os.Stderr, "deprecated: %s\n", cmd.Deprecated)
+			}
+		}
+
+		if 0 != cmdCount {
 			fmt.Fprintln(os.Stderr)
 			fmt.Fprintln(os.Stderr, "commands:")
 			cmdmap.PrintCmds()
-			fmt.Fprintln(os.Stderr)
-			fmt.Fprintln(os.Stderr, "options:")
+		}
+
+		if 0 != flagCount {
+			if 0 != cmdCount {
+				fmt.Fprintln(os.Stderr)
+				fmt.Fprintln(os.Stderr, "options:")
+			}
 			flagSet.PrintDefaults()
 		}
 	}
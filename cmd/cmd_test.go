@@ -0,0 +1,402 @@
+/*
+ * cmd_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+func TestSubCmd(t *testing.T) {
+	var (
+		path    string
+		verbose interface{}
+		name    interface{}
+		args    []string
+	)
+
+	cmdmap := NewCmdMap()
+	remote := cmdmap.Add("remote [-v] command args...\nmanage remotes", nil)
+	remote.Flag.Bool("v", false, "verbose")
+	add := cmdmap.Add("remote.add [-n name] url\nadd a remote", func(cmd *Cmd, a []string) {
+		cmd.Flag.Parse(a)
+		path = cmd.Path()
+		verbose = cmd.GetFlag("v")
+		name = cmd.GetFlag("n")
+		args = cmd.Flag.Args()
+	})
+	add.Flag.String("n", "", "name")
+
+	if remote != add.Parent() {
+		t.Error()
+	}
+	if "remote add [-n name] url" != add.Use {
+		t.Error()
+	}
+	if !reflect.DeepEqual([]string{"remote"}, cmdmap.GetNames()) {
+		t.Error()
+	}
+	if !reflect.DeepEqual([]string{"add"}, remote.Sub.GetNames()) {
+		t.Error()
+	}
+
+	rm := remote.Sub.Add("rm name\nremove a remote", nil)
+	if "remote rm name" != rm.Use || "remote rm" != rm.Path() {
+		t.Error()
+	}
+
+	flagSet := flag.NewFlagSet("test", flag.ExitOnError)
+	cmdmap.Run(flagSet, []string{"remote", "-v", "add", "-n", "origin", "URL"})
+	if "remote add" != path {
+		t.Error()
+	}
+	if true != verbose || "origin" != name {
+		t.Error()
+	}
+	if !reflect.DeepEqual([]string{"URL"}, args) {
+		t.Error()
+	}
+
+	if rm != cmdmap.lookup([]string{"remote", "rm"}) {
+		t.Error()
+	}
+	if nil != cmdmap.lookup([]string{"remote", "xx"}) {
+		t.Error()
+	}
+}
+
+func TestSubCmdMain(t *testing.T) {
+	var (
+		ran     string
+		args    []string
+		verbose interface{}
+	)
+
+	cmdmap := NewCmdMap()
+	remote := cmdmap.Add("remote", func(cmd *Cmd, a []string) {
+		cmd.Flag.Parse(a)
+		ran = "remote"
+		args = cmd.Flag.Args()
+		verbose = cmd.GetFlag("v")
+	})
+	remote.Flag.Bool("v", false, "verbose")
+	cmdmap.Add("remote.add", func(cmd *Cmd, a []string) {
+		ran = "add"
+		args = a
+		verbose = cmd.GetFlag("v")
+	})
+
+	run := func(a ...string) {
+		remote.Flag.Set("v", "false")
+		cmdmap.Run(flag.NewFlagSet("test", flag.ExitOnError), a)
+	}
+
+	run("remote", "show", "x")
+	if "remote" != ran || !reflect.DeepEqual([]string{"show", "x"}, args) || false != verbose {
+		t.Error()
+	}
+
+	run("remote", "-v", "show")
+	if "remote" != ran || !reflect.DeepEqual([]string{"show"}, args) || true != verbose {
+		t.Error()
+	}
+
+	// Main parses the original arguments, so "--" is still honored
+	run("remote", "--", "-v")
+	if "remote" != ran || !reflect.DeepEqual([]string{"-v"}, args) || false != verbose {
+		t.Error(args, verbose)
+	}
+
+	run("remote", "-v", "add", "x")
+	if "add" != ran || !reflect.DeepEqual([]string{"x"}, args) || true != verbose {
+		t.Error()
+	}
+	// sub-commands are also found by unambiguous prefix
+	run("remote", "ad", "y")
+	if "add" != ran || !reflect.DeepEqual([]string{"y"}, args) || false != verbose {
+		t.Error()
+	}
+}
+
+func TestFlatCmd(t *testing.T) {
+	// without a "remote" command dotted names register the last word
+	cmdmap := NewCmdMap()
+	cmd := cmdmap.Add("remote.add url", nil)
+	if "remote add url" != cmd.Use || nil != cmd.Parent() {
+		t.Error()
+	}
+	if !reflect.DeepEqual([]string{"add"}, cmdmap.GetNames()) {
+		t.Error()
+	}
+}
+
+func TestRunE(t *testing.T) {
+	// silence usage output
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() {
+		os.Stderr = stderr
+	}()
+
+	failure := errors.New("failure")
+
+	cmdmap := NewCmdMap()
+	c := cmdmap.AddE("test [-x]", func(cmd *Cmd, args []string) error {
+		err := cmd.Parse(args)
+		if nil != err {
+			return err
+		}
+		if 0 != cmd.Flag.NArg() {
+			return failure
+		}
+		return nil
+	})
+	c.Flag.Bool("x", false, "x")
+	c.Flag.SetOutput(ioutil.Discard)
+	cmdmap.AddE("group", nil)
+
+	run := func(args ...string) error {
+		flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		return cmdmap.RunE(flagSet, args)
+	}
+
+	err := run("test", "-x")
+	if nil != err || 0 != ExitCode(err) {
+		t.Error(err)
+	}
+
+	err = run("test", "arg")
+	if failure != err || 1 != ExitCode(err) {
+		t.Error(err)
+	}
+
+	err = run("test", "-y")
+	if !errors.HasAttachment(err, ErrFlag) || 2 != ExitCode(err) {
+		t.Error(err)
+	}
+
+	err = run("test", "-h")
+	if !errors.HasAttachment(err, ErrHelp) {
+		t.Error(err)
+	}
+
+	err = run("help", "test")
+	if !errors.HasAttachment(err, ErrHelp) {
+		t.Error(err)
+	}
+
+	err = run("unknown")
+	if !errors.HasAttachment(err, ErrUnknownCommand) || 2 != ExitCode(err) {
+		t.Error(err)
+	}
+
+	err = run()
+	if !errors.HasAttachment(err, ErrUnknownCommand) {
+		t.Error(err)
+	}
+
+	err = run("group")
+	if !errors.HasAttachment(err, ErrUnknownCommand) {
+		t.Error(err)
+	}
+
+	err = errors.New("outer", errors.New("inner", nil, ErrFlag), []string{"x"})
+	if 2 != ExitCode(err) {
+		t.Error(err)
+	}
+	err = errors.New("outer", nil, map[string]int{})
+	if 1 != ExitCode(err) {
+		t.Error(err)
+	}
+
+	ExitCodes[ErrHelp] = 0
+	defer func() {
+		ExitCodes[ErrHelp] = 2
+	}()
+	err = run("help")
+	if !errors.HasAttachment(err, ErrHelp) || 0 != ExitCode(err) {
+		t.Error(err)
+	}
+}
+
+func TestCompletions(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.Bool("v", false, "verbose")
+
+	cmdmap := NewCmdMap()
+	remote := cmdmap.Add("remote", nil)
+	remote.Flag.Bool("q", false, "quiet")
+	add := cmdmap.Add("remote.add", nil)
+	add.Flag.String("name", "", "name")
+	add.Flag.Bool("force", false, "force")
+	add.Complete = func(cmd *Cmd, args []string, word string) []string {
+		if 0 < len(args) && "-name" == args[len(args)-1] {
+			return []string{"origin", "upstream"}
+		}
+		return []string{"url" + word + "/" + strings.Join(args, ",")}
+	}
+	cmdmap.Add("remove", nil)
+
+	tests := []struct {
+		words []string
+		comps []string
+	}{
+		{[]string{}, []string{"remote", "remove"}},
+		{[]string{""}, []string{"remote", "remove"}},
+		{[]string{"rem"}, []string{"remote", "remove"}},
+		{[]string{"remo"}, []string{"remote", "remove"}},
+		{[]string{"-"}, []string{"-v"}},
+		{[]string{"-v", "remote", ""}, []string{"add"}},
+		{[]string{"remote", "-"}, []string{"-q"}},
+		{[]string{"remote", "add", "-"}, []string{"-force", "-name"}},
+		{[]string{"remote", "add", "-name", ""}, []string{"origin", "upstream"}},
+		{[]string{"remote", "add", "-name", "o"}, []string{"origin"}},
+		{[]string{"remote", "add", "-force", "x", "u"}, []string{"urlu/-force,x"}},
+		{[]string{"remote", "add", "-force", "x", "-"}, []string{}},
+		{[]string{"remove", ""}, []string{}},
+	}
+	for _, test := range tests {
+		comps := cmdmap.Completions(flagSet, test.words)
+		if !reflect.DeepEqual(test.comps, comps) {
+			t.Error(test.words, comps)
+		}
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	cmdmap := NewCmdMap()
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		buf := &bytes.Buffer{}
+		err := cmdmap.WriteCompletion(buf, shell, "my-tool")
+		if nil != err {
+			t.Error(err)
+		}
+		s := buf.String()
+		if !strings.Contains(s, "my-tool __complete") || !strings.Contains(s, "_my_tool_complete") {
+			t.Error(shell, s)
+		}
+	}
+
+	err := cmdmap.WriteCompletion(&bytes.Buffer{}, "csh", "my-tool")
+	if !errors.HasAttachment(err, ErrFlag) || 2 != ExitCode(err) {
+		t.Error(err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	cmdmap := NewCmdMap()
+	remote := cmdmap.Add("remote", nil)
+	remove := cmdmap.Add("remove", nil)
+	remove.Aliases = []string{"rm", "del"}
+	debug := cmdmap.Add("debug", nil)
+	debug.Hidden = true
+
+	tests := []struct {
+		name string
+		cmd  *Cmd
+	}{
+		{"remote", remote},
+		{"remov", remove},
+		{"rm", remove},
+		{"de", remove},
+		{"debug", debug},
+		{"deb", nil},
+		{"rem", nil},
+		{"r", nil},
+		{"x", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		cmd, err := cmdmap.Find(test.name)
+		if test.cmd != cmd {
+			t.Error(test.name)
+		}
+		if nil == cmd && !errors.HasAttachment(err, ErrUnknownCommand) {
+			t.Error(test.name, err)
+		}
+	}
+
+	if remove != cmdmap.Get("del") || nil != cmdmap.Get("de") {
+		t.Error()
+	}
+	if !reflect.DeepEqual([]string{"remote", "remove"}, cmdmap.visibleNames()) {
+		t.Error()
+	}
+	if !reflect.DeepEqual([]string{"remote", "remove"}, cmdmap.Completions(nil, []string{""})) {
+		t.Error()
+	}
+}
+
+func TestRunAliases(t *testing.T) {
+	// silence usage and warning output
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() {
+		os.Stderr = stderr
+	}()
+
+	var runs []string
+	cmdmap := NewCmdMap()
+	cmdmap.Add("remote", nil)
+	cmdmap.AddE("remote.add", func(cmd *Cmd, args []string) error {
+		runs = append(runs, "add")
+		return nil
+	}).Aliases = []string{"new"}
+	cmdmap.AddE("remote.create", func(cmd *Cmd, args []string) error {
+		runs = append(runs, "create")
+		return nil
+	}).Deprecated = "use remote add instead"
+	cmdmap.AddE("status", func(cmd *Cmd, args []string) error {
+		runs = append(runs, "status")
+		return nil
+	})
+
+	run := func(args ...string) error {
+		flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
+		return cmdmap.RunE(flagSet, args)
+	}
+
+	for _, args := range [][]string{
+		{"remote", "add"},
+		{"rem", "new"},
+		{"st"},
+		{"remote", "cr"},
+	} {
+		err := run(args...)
+		if nil != err {
+			t.Error(args, err)
+		}
+	}
+	if !reflect.DeepEqual([]string{"add", "add", "status", "create"}, runs) {
+		t.Error(runs)
+	}
+
+	err := run("remote", "x")
+	if !errors.HasAttachment(err, ErrUnknownCommand) {
+		t.Error(err)
+	}
+
+	err = run("help", "rem", "a")
+	if !errors.HasAttachment(err, ErrHelp) {
+		t.Error(err)
+	}
+}
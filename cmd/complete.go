@@ -0,0 +1,201 @@
+/*
+ * complete.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// completeCmd is the name of the hidden command used by the completion
+// scripts. It is invoked as:
+//
+//	PROGNAME __complete WORDS... CURRENT
+//
+// where WORDS are the command line words that precede the word being
+// completed and CURRENT is the (possibly empty) word being completed.
+// The completions are printed to stdout one per line.
+const completeCmd = "__complete"
+
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(boolFlag)
+	return ok && b.IsBoolFlag()
+}
+
+// Completions returns the completions for the last of the passed command
+// line words (which does not include the program name). The completions
+// consist of the names of the commands and the flags registered in the
+// command map, as well as any completions returned by the Complete
+// function of the selected command.
+//
+// The flagSet parameter contains the program flags that may precede the
+// command name. It may be nil.
+func (self *CmdMap) Completions(flagSet *flag.FlagSet, words []string) []string {
+	if 0 == len(words) {
+		words = []string{""}
+	}
+
+	cmdmap := self
+	cmd := (*Cmd)(nil)
+	fs := flagSet
+	args := []string(nil)
+	flags := true
+	value := false
+	for _, word := range words[:len(words)-1] {
+		switch {
+		case value:
+			value = false
+			args = append(args, word)
+		case flags && "--" == word:
+			cmdmap = nil
+			flags = false
+			args = append(args, word)
+		case flags && 1 < len(word) && '-' == word[0]:
+			name := strings.TrimLeft(word, "-")
+			if nil != fs && -1 == strings.IndexByte(name, '=') {
+				if f := fs.Lookup(name); nil != f && !isBoolFlag(f) {
+					value = true
+				}
+			}
+			args = append(args, word)
+		case nil != cmdmap && nil != find(cmdmap, word):
+			cmd = find(cmdmap, word)
+			cmdmap = cmd.Sub
+			fs = cmd.Flag
+			args = nil
+		default:
+			cmdmap = nil
+			flags = false
+			args = append(args, word)
+		}
+	}
+
+	word := words[len(words)-1]
+	var cands []string
+	switch {
+	case value:
+	case flags && strings.HasPrefix(word, "-"):
+		if nil != fs {
+			fs.VisitAll(func(f *flag.Flag) {
+				cands = append(cands, "-"+f.Name)
+			})
+		}
+		return filterPrefix(cands, word)
+	case nil != cmdmap:
+		cands = append(cands, cmdmap.visibleNames()...)
+	}
+
+	if nil != cmd && nil != cmd.Complete {
+		cands = append(cands, cmd.Complete(cmd, args, word)...)
+	}
+
+	return filterPrefix(cands, word)
+}
+
+func find(cmdmap *CmdMap, name string) *Cmd {
+	cmd, _ := cmdmap.Find(name)
+	return cmd
+}
+
+func filterPrefix(cands []string, prefix string) []string {
+	res := []string{}
+	for _, c := range cands {
+		if strings.HasPrefix(c, prefix) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func (self *CmdMap) complete(flagSet *flag.FlagSet, words []string) {
+	for _, c := range self.Completions(flagSet, words) {
+		fmt.Println(c)
+	}
+}
+
+var completionScripts = map[string]string{
+	"bash": `# bash completion for PROGNAME
+FUNCNAME() {
+    local IFS=$'\n'
+    COMPREPLY=($(PROGNAME __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F FUNCNAME PROGNAME
+`,
+	"zsh": `#compdef PROGNAME
+# zsh completion for PROGNAME
+FUNCNAME() {
+    local -a completions
+    completions=(${(f)"$(PROGNAME __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    if (( ${#completions} )); then
+        compadd -a completions
+    else
+        _files
+    fi
+}
+if [ "$funcstack[1]" = "FUNCNAME" ]; then
+    FUNCNAME "$@"
+else
+    compdef FUNCNAME PROGNAME
+fi
+`,
+	"fish": `# fish completion for PROGNAME
+function FUNCNAME
+    set -l words (commandline -opc)
+    set -l current (commandline -ct)
+    set -e words[1]
+    PROGNAME __complete $words "$current" 2>/dev/null
+end
+complete -c PROGNAME -f -a '(FUNCNAME)'
+`,
+}
+
+// WriteCompletion writes a completion script for the specified shell
+// ("bash", "zsh" or "fish"). The script completes the command line of
+// the program progname by invoking the program with the hidden
+// __complete command, which is handled by RunE using Completions.
+// An unknown shell is reported as an error with the ErrFlag attachment.
+func (self *CmdMap) WriteCompletion(writer io.Writer, shell string, progname string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return errors.New("unknown shell "+shell, nil, ErrFlag)
+	}
+
+	funcname := []byte("_" + progname + "_complete")
+	for i, c := range funcname {
+		if !('A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9') {
+			funcname[i] = '_'
+		}
+	}
+
+	script = strings.NewReplacer(
+		"FUNCNAME", string(funcname),
+		"PROGNAME", progname).Replace(script)
+	_, err := io.WriteString(writer, script)
+	return err
+}
+
+// WriteCompletion writes a completion script for the specified shell
+// for the default command map and the current program.
+func WriteCompletion(writer io.Writer, shell string) error {
+	return DefaultCmdMap.WriteCompletion(writer, shell, filepath.Base(os.Args[0]))
+}
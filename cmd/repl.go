@@ -0,0 +1,232 @@
+/*
+ * repl.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/billziss-gh/golib/appdata"
+	"github.com/billziss-gh/golib/errors"
+	"github.com/billziss-gh/golib/shlex"
+	"github.com/billziss-gh/golib/terminal/editor"
+	"github.com/billziss-gh/golib/util"
+)
+
+// Repl runs an interactive shell for the command map. Every line read
+// from the editor is split into arguments using shlex.Posix and executed
+// using RunE; errors are printed to stderr and do not end the shell.
+// The shell ends on EOF (^D on Unix, ^Z on Windows) or when the exit or
+// quit command is entered, unless the command map contains a command with
+// that name. The line being edited is discarded on ^C.
+//
+// The editor completion handler is set to complete command and flag names
+// (see Completions). If histpath is not empty, the command line history
+// is read from this file when the shell starts and written to it after
+// every command; only a failure to read an existing history file ends the
+// shell. The number of command lines kept is determined by the capacity
+// of the editor history (see editor.History.SetCap).
+//
+// The values of the flags of all commands are recorded when the shell
+// starts. Before every command the flag sets of all commands are replaced
+// by new flag sets created with flag.ContinueOnError, which contain the same
+// flags restored to their recorded values; flag errors and -h therefore do
+// not end the shell. Flag values that cannot be recorded (because they are
+// not pointers) are reset using their default value. Commands that are run
+// in a shell should be added using AddE and return errors rather than exit
+// the program.
+func (self *CmdMap) Repl(ed *editor.Editor, prompt string, histpath string) error {
+	ed.SetCompletionHandler(self.completeLine)
+	snap := flagSnapshot{}
+	snap.record(self)
+
+	if "" != histpath {
+		_, err := util.ReadFunc(histpath, func(file *os.File) (interface{}, error) {
+			return nil, ed.History().Read(file)
+		})
+		if nil != err && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	for {
+		line, err := ed.GetLine(prompt)
+		if io.ErrUnexpectedEOF == err {
+			continue
+		}
+		eof := io.EOF == err
+		if nil != err && !eof {
+			return err
+		}
+
+		args := shlex.Posix.Split(line)
+		if 0 == len(args) {
+			if eof {
+				return nil
+			}
+			continue
+		}
+
+		ed.History().Add(line)
+		if "" != histpath {
+			err = util.WriteFunc(histpath, 0600, func(file *os.File) error {
+				return ed.History().Write(file)
+			})
+			if nil != err {
+				printError(errors.New("cannot write history", err))
+			}
+		}
+
+		if nil == self.Get(args[0]) && ("exit" == args[0] || "quit" == args[0]) {
+			return nil
+		}
+
+		err = snap.restore(self)
+		if nil != err {
+			return err
+		}
+		flagSet := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+		flagSet.Usage = UsageFunc(self)
+		err = self.RunE(flagSet, args)
+		if nil != err {
+			printError(err)
+		}
+
+		if eof {
+			return nil
+		}
+	}
+}
+
+// completeLine is the editor completion handler. It returns complete
+// lines that consist of the line up to the last word followed by the
+// completions of the last word.
+func (self *CmdMap) completeLine(line string) []string {
+	words := shlex.Posix.Split(line)
+	i := strings.LastIndexAny(line, " \t") + 1
+	if len(line) == i {
+		words = append(words, "")
+	}
+
+	var lines []string
+	for _, c := range self.Completions(nil, words) {
+		lines = append(lines, line[:i]+c)
+	}
+	return lines
+}
+
+// flagSnapshot records the values of flags, so that they can be restored
+// between the commands of a shell. It maps flag values that are pointers
+// to copies of the values they point to.
+type flagSnapshot map[flag.Value]reflect.Value
+
+// pointer returns the value that a flag value points to, if any.
+func pointer(v flag.Value) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if reflect.Ptr != rv.Kind() || rv.IsNil() {
+		return reflect.Value{}, false
+	}
+	return rv.Elem(), true
+}
+
+// record records the flag values of all commands (recursively).
+func (snap flagSnapshot) record(cmdmap *CmdMap) {
+	for _, name := range cmdmap.GetNames() {
+		cmd := cmdmap.Get(name)
+		if nil == cmd {
+			continue
+		}
+
+		cmd.Flag.VisitAll(func(f *flag.Flag) {
+			if elem, ok := pointer(f.Value); ok {
+				saved := reflect.New(elem.Type()).Elem()
+				saved.Set(elem)
+				snap[f.Value] = saved
+			}
+		})
+
+		if nil != cmd.Sub {
+			snap.record(cmd.Sub)
+		}
+	}
+}
+
+// restore replaces the flag sets of all commands (recursively) with new
+// flag sets that contain the same flags restored to their recorded values.
+func (snap flagSnapshot) restore(cmdmap *CmdMap) (err error) {
+	for _, name := range cmdmap.GetNames() {
+		cmd := cmdmap.Get(name)
+		if nil == cmd {
+			continue
+		}
+
+		flagSet := flag.NewFlagSet(cmd.Flag.Name(), flag.ContinueOnError)
+		flagSet.SetOutput(cmd.Flag.Output())
+		flagSet.Usage = cmd.Flag.Usage
+		cmd.Flag.VisitAll(func(f *flag.Flag) {
+			if elem, ok := pointer(f.Value); ok && snap[f.Value].IsValid() {
+				elem.Set(snap[f.Value])
+			} else if e := f.Value.Set(f.DefValue); nil != e && nil == err {
+				err = errors.New(fmt.Sprintf("cannot reset flag -%s", f.Name), e)
+			}
+			flagSet.Var(f.Value, f.Name, f.Usage)
+			flagSet.Lookup(f.Name).DefValue = f.DefValue
+		})
+		cmd.Flag = flagSet
+
+		if nil != cmd.Sub {
+			if e := snap.restore(cmd.Sub); nil != e && nil == err {
+				err = e
+			}
+		}
+	}
+	return
+}
+
+// Repl runs an interactive shell for the default command map using the
+// default editor. Up to 1000 command lines of history are kept in the file
+// PROGNAME/history of the application data directory (see appdata.DataDir).
+func Repl() error {
+	editor.DefaultEditor.History().SetCap(1000)
+	progname := filepath.Base(os.Args[0])
+	histpath := ""
+	if dir, err := appdata.DataDir(); nil == err {
+		histpath = filepath.Join(dir, progname, "history")
+	}
+	return DefaultCmdMap.Repl(editor.DefaultEditor, progname+"> ", histpath)
+}
+
+// RunRepl parses the command line and executes the specified (sub-)command
+// from the default command map like Run. If no command is specified it
+// runs an interactive shell instead (see Repl).
+func RunRepl() {
+	if !flag.CommandLine.Parsed() {
+		flag.CommandLine.Parse(os.Args[1:])
+	}
+
+	if 0 == flag.NArg() {
+		err := Repl()
+		if nil != err {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	Run()
+}
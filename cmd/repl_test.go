@@ -0,0 +1,295 @@
+/*
+ * repl_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billziss-gh/golib/config"
+	cflag "github.com/billziss-gh/golib/config/flag"
+	"github.com/billziss-gh/golib/errors"
+	"github.com/billziss-gh/golib/terminal/editor"
+)
+
+func TestRepl(t *testing.T) {
+	// silence usage and error output
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() {
+		os.Stderr = stderr
+	}()
+
+	dir, err := ioutil.TempDir("", "repl_test")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	histpath := filepath.Join(dir, "tool", "history")
+
+	var runs []string
+	cmdmap := NewCmdMap()
+	c := cmdmap.AddE("echo [-n] args...", func(cmd *Cmd, args []string) error {
+		err := cmd.Parse(args)
+		if nil != err {
+			return err
+		}
+		s := strings.Join(cmd.Flag.Args(), "|")
+		if cmd.GetFlag("n").(bool) {
+			s = "n:" + s
+		}
+		runs = append(runs, s)
+		return nil
+	})
+	c.Flag.Bool("n", false, "no newline")
+	c.Flag.SetOutput(ioutil.Discard)
+	cmdmap.AddE("fail", func(cmd *Cmd, args []string) error {
+		runs = append(runs, "fail")
+		return errors.New("failure")
+	})
+
+	input := "echo -n 'a b' c\n" +
+		"\n" +
+		"echo d\n" +
+		"echo -x\n" +
+		"unknown\n" +
+		"fail\n" +
+		"echo \"e f\"\n" +
+		"exit\n" +
+		"echo g\n"
+
+	repl := func(input string) (*editor.Editor, error) {
+		in, out, err := os.Pipe()
+		if nil != err {
+			return nil, err
+		}
+		done := make(chan struct{})
+		go func() {
+			out.WriteString(input)
+			out.Close()
+			close(done)
+		}()
+		ed := editor.NewEditor(in, os.Stdout)
+		ed.History().SetCap(100)
+		err = cmdmap.Repl(ed, "> ", histpath)
+		in.Close()
+		<-done
+		return ed, err
+	}
+
+	_, err = repl(input)
+	if nil != err {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual([]string{"n:a b|c", "d", "fail", "e f"}, runs) {
+		t.Error(runs)
+	}
+
+	data, err := ioutil.ReadFile(histpath)
+	if nil != err {
+		t.Error(err)
+	}
+	hist := "echo -n 'a b' c\necho d\necho -x\nunknown\nfail\necho \"e f\"\nexit\n"
+	if hist != string(data) {
+		t.Error(string(data))
+	}
+
+	// history is read when the shell starts; input without final newline
+	runs = nil
+	ed, err := repl("echo h")
+	if nil != err {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual([]string{"h"}, runs) {
+		t.Error(runs)
+	}
+	if 8 != ed.History().Len() {
+		t.Error(ed.History().Len())
+	}
+}
+
+func TestReplHistoryError(t *testing.T) {
+	// silence error output
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() {
+		os.Stderr = stderr
+	}()
+
+	dir, err := ioutil.TempDir("", "repl_test")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	histdir := filepath.Join(dir, "tool")
+	histpath := filepath.Join(histdir, "history")
+
+	var runs []string
+	cmdmap := NewCmdMap()
+	cmdmap.AddE("break", func(cmd *Cmd, args []string) error {
+		// replace the history directory with a file
+		runs = append(runs, "break")
+		os.RemoveAll(histdir)
+		return ioutil.WriteFile(histdir, nil, 0600)
+	})
+	cmdmap.AddE("echo args...", func(cmd *Cmd, args []string) error {
+		runs = append(runs, strings.Join(args, "|"))
+		return nil
+	})
+
+	in, out, err := os.Pipe()
+	if nil != err {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		out.WriteString("break\necho a\necho b\n")
+		out.Close()
+		close(done)
+	}()
+	err = cmdmap.Repl(editor.NewEditor(in, os.Stdout), "> ", histpath)
+	in.Close()
+	<-done
+	if nil != err {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual([]string{"break", "a", "b"}, runs) {
+		t.Error(runs)
+	}
+}
+
+func TestReplFlags(t *testing.T) {
+	// silence usage and error output
+	stderr := os.Stderr
+	os.Stderr, _ = os.Open(os.DevNull)
+	defer func() {
+		os.Stderr = stderr
+	}()
+
+	var runs []string
+	cmdmap := NewCmdMap()
+	c := cmdmap.AddE("show [-x list] [-s string]", func(cmd *Cmd, args []string) error {
+		err := cmd.Parse(args)
+		if nil != err {
+			return err
+		}
+		runs = append(runs, fmt.Sprint(cmd.GetFlag("x"), " ", cmd.GetFlag("s")))
+		return nil
+	})
+	section := config.TypedSection{"x": []interface{}{"a", "b"}}
+	err := cflag.Register(c.Flag, section, "x")
+	if nil != err {
+		t.Fatal(err)
+	}
+	c.Flag.String("s", "def", "string")
+	usage := false
+	c.Flag.Usage = func() {
+		usage = nil != c.Flag.Lookup("x") && c.Flag == cmdmap.Get("show").Flag
+	}
+
+	in, out, err := os.Pipe()
+	if nil != err {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		out.WriteString("show\nshow -x c -s v\nshow -x d -x e\nshow\nshow -h\n")
+		out.Close()
+		close(done)
+	}()
+	err = cmdmap.Repl(editor.NewEditor(in, os.Stdout), "> ", "")
+	in.Close()
+	<-done
+	if nil != err {
+		t.Error(err)
+	}
+
+	expected := []string{"[a b] def", "[c] v", "[d e] def", "[a b] def"}
+	if !reflect.DeepEqual(expected, runs) {
+		t.Error(runs)
+	}
+	if !usage {
+		t.Error()
+	}
+	if "a,b" != c.Flag.Lookup("x").DefValue {
+		t.Error(c.Flag.Lookup("x").DefValue)
+	}
+}
+
+func TestReplExit(t *testing.T) {
+	var runs []string
+	cmdmap := NewCmdMap()
+	cmdmap.AddE("exit", func(cmd *Cmd, args []string) error {
+		runs = append(runs, "exit")
+		return nil
+	})
+	cmdmap.AddE("echo args...", func(cmd *Cmd, args []string) error {
+		runs = append(runs, strings.Join(args, "|"))
+		return nil
+	})
+
+	in, out, err := os.Pipe()
+	if nil != err {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		out.WriteString("exit\necho a\nquit\necho b\n")
+		out.Close()
+		close(done)
+	}()
+	err = cmdmap.Repl(editor.NewEditor(in, os.Stdout), "> ", "")
+	in.Close()
+	<-done
+	if nil != err {
+		t.Error(err)
+	}
+
+	if !reflect.DeepEqual([]string{"exit", "a"}, runs) {
+		t.Error(runs)
+	}
+}
+
+func TestCompleteLine(t *testing.T) {
+	cmdmap := NewCmdMap()
+	remote := cmdmap.Add("remote", nil)
+	cmdmap.Add("remote.add", nil).Flag.Bool("force", false, "force")
+	cmdmap.Add("remote.rm", nil)
+	remote.Flag.Bool("v", false, "verbose")
+
+	tests := []struct {
+		line  string
+		lines []string
+	}{
+		{"", []string{"remote"}},
+		{"re", []string{"remote"}},
+		{"remote ", []string{"remote add", "remote rm"}},
+		{"remote  a", []string{"remote  add"}},
+		{"remote -", []string{"remote -v"}},
+		{"remote add -f", []string{"remote add -force"}},
+		{"x", nil},
+	}
+	for _, test := range tests {
+		lines := cmdmap.completeLine(test.line)
+		if !reflect.DeepEqual(test.lines, lines) {
+			t.Error(test.line, lines)
+		}
+	}
+}
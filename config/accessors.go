@@ -0,0 +1,321 @@
+/*
+ * accessors.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// lookup gets a property value and determines whether it exists.
+func (conf Config) lookup(k string) (interface{}, bool) {
+	s, n := splitName(k)
+	v, ok := conf[s][n]
+	return v, ok
+}
+
+// lookup gets a property value and determines whether it exists.
+func (conf TypedConfig) lookup(k string) (interface{}, bool) {
+	s, n := splitName(k)
+	v, ok := conf[s][n]
+	return v, ok
+}
+
+func convertError(k string, typ string, err error) error {
+	return errors.New(fmt.Sprintf("cannot convert %s to %s", k, typ), err, ErrConfig)
+}
+
+func lookupString(k string, valu interface{}, ok bool) (string, bool, error) {
+	if !ok {
+		return "", false, nil
+	}
+	v, err := toString(valu)
+	if nil != err {
+		return "", true, convertError(k, "string", err)
+	}
+	return v, true, nil
+}
+
+func lookupInt(k string, valu interface{}, ok bool) (int64, bool, error) {
+	if !ok {
+		return 0, false, nil
+	}
+	v, err := toInt(valu)
+	if nil != err {
+		return 0, true, convertError(k, "integer", err)
+	}
+	return v, true, nil
+}
+
+func lookupFloat(k string, valu interface{}, ok bool) (float64, bool, error) {
+	if !ok {
+		return 0, false, nil
+	}
+	v, err := toFloat(valu)
+	if nil != err {
+		return 0, true, convertError(k, "float", err)
+	}
+	return v, true, nil
+}
+
+func lookupBool(k string, valu interface{}, ok bool) (bool, bool, error) {
+	if !ok {
+		return false, false, nil
+	}
+	v, err := toBool(valu)
+	if nil != err {
+		return false, true, convertError(k, "boolean", err)
+	}
+	return v, true, nil
+}
+
+func lookupDuration(k string, valu interface{}, ok bool) (time.Duration, bool, error) {
+	if !ok {
+		return 0, false, nil
+	}
+	v, err := toDuration(valu)
+	if nil != err {
+		return 0, true, convertError(k, "duration", err)
+	}
+	return v, true, nil
+}
+
+func lookupList(k string, valu interface{}, ok bool) ([]interface{}, bool, error) {
+	if !ok {
+		return nil, false, nil
+	}
+	v, err := toList(valu)
+	if nil != err {
+		return nil, true, convertError(k, "list", err)
+	}
+	return v, true, nil
+}
+
+// LookupString gets a property as a string. It reports whether the
+// property exists and returns an error if it cannot be converted.
+func (conf Config) LookupString(k string) (string, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupString(k, v, ok)
+}
+
+// LookupInt gets a property as an integer. It reports whether the
+// property exists and returns an error if it cannot be converted.
+func (conf Config) LookupInt(k string) (int64, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupInt(k, v, ok)
+}
+
+// LookupFloat gets a property as a floating point number. It reports
+// whether the property exists and returns an error if it cannot be
+// converted.
+func (conf Config) LookupFloat(k string) (float64, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupFloat(k, v, ok)
+}
+
+// LookupBool gets a property as a boolean. It reports whether the
+// property exists and returns an error if it cannot be converted.
+// An empty value is true, because empty keys are read as empty values.
+func (conf Config) LookupBool(k string) (bool, bool, error) {
+	v, ok := conf.lookup(k)
+	if "" == v {
+		v = true
+	}
+	return lookupBool(k, v, ok)
+}
+
+// LookupDuration gets a property as a duration. It reports whether the
+// property exists and returns an error if it cannot be converted.
+func (conf Config) LookupDuration(k string) (time.Duration, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupDuration(k, v, ok)
+}
+
+// LookupList gets a property as a list. Values are split on commas.
+// It reports whether the property exists.
+func (conf Config) LookupList(k string) ([]interface{}, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupList(k, v, ok)
+}
+
+// GetString gets a property as a string or returns def if it is missing.
+func (conf Config) GetString(k string, def string) (string, error) {
+	v, ok, err := conf.LookupString(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetInt gets a property as an integer or returns def if it is missing.
+// If the property cannot be converted GetInt returns def and an error.
+func (conf Config) GetInt(k string, def int64) (int64, error) {
+	v, ok, err := conf.LookupInt(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetFloat gets a property as a floating point number or returns def if
+// it is missing. If the property cannot be converted GetFloat returns def
+// and an error.
+func (conf Config) GetFloat(k string, def float64) (float64, error) {
+	v, ok, err := conf.LookupFloat(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetBool gets a property as a boolean or returns def if it is missing.
+// If the property cannot be converted GetBool returns def and an error.
+func (conf Config) GetBool(k string, def bool) (bool, error) {
+	v, ok, err := conf.LookupBool(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetDuration gets a property as a duration or returns def if it is
+// missing. If the property cannot be converted GetDuration returns def
+// and an error.
+func (conf Config) GetDuration(k string, def time.Duration) (time.Duration, error) {
+	v, ok, err := conf.LookupDuration(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetList gets a property as a list or returns def if it is missing.
+func (conf Config) GetList(k string, def []interface{}) ([]interface{}, error) {
+	v, ok, err := conf.LookupList(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// LookupString gets a property as a string. It reports whether the
+// property exists and returns an error if it cannot be converted.
+// Non-string values are formatted as text; lists cannot be converted.
+func (conf TypedConfig) LookupString(k string) (string, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupString(k, v, ok)
+}
+
+// LookupInt gets a property as an integer. It reports whether the
+// property exists and returns an error if it cannot be converted.
+// Strings are parsed; floating point numbers must be integral.
+func (conf TypedConfig) LookupInt(k string) (int64, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupInt(k, v, ok)
+}
+
+// LookupFloat gets a property as a floating point number. It reports
+// whether the property exists and returns an error if it cannot be
+// converted.
+func (conf TypedConfig) LookupFloat(k string) (float64, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupFloat(k, v, ok)
+}
+
+// LookupBool gets a property as a boolean. It reports whether the
+// property exists and returns an error if it cannot be converted.
+func (conf TypedConfig) LookupBool(k string) (bool, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupBool(k, v, ok)
+}
+
+// LookupDuration gets a property as a duration. It reports whether the
+// property exists and returns an error if it cannot be converted.
+// Strings are parsed using time.ParseDuration; integers other than 0 are
+// rejected, because a duration without unit is ambiguous.
+func (conf TypedConfig) LookupDuration(k string) (time.Duration, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupDuration(k, v, ok)
+}
+
+// LookupList gets a property as a list. Strings are split on commas and
+// other values become single element lists. It reports whether the
+// property exists.
+func (conf TypedConfig) LookupList(k string) ([]interface{}, bool, error) {
+	v, ok := conf.lookup(k)
+	return lookupList(k, v, ok)
+}
+
+// GetString gets a property as a string or returns def if it is missing.
+// If the property cannot be converted GetString returns def and an error.
+func (conf TypedConfig) GetString(k string, def string) (string, error) {
+	v, ok, err := conf.LookupString(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetInt gets a property as an integer or returns def if it is missing.
+// If the property cannot be converted GetInt returns def and an error.
+func (conf TypedConfig) GetInt(k string, def int64) (int64, error) {
+	v, ok, err := conf.LookupInt(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetFloat gets a property as a floating point number or returns def if
+// it is missing. If the property cannot be converted GetFloat returns def
+// and an error.
+func (conf TypedConfig) GetFloat(k string, def float64) (float64, error) {
+	v, ok, err := conf.LookupFloat(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetBool gets a property as a boolean or returns def if it is missing.
+// If the property cannot be converted GetBool returns def and an error.
+func (conf TypedConfig) GetBool(k string, def bool) (bool, error) {
+	v, ok, err := conf.LookupBool(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetDuration gets a property as a duration or returns def if it is
+// missing. If the property cannot be converted GetDuration returns def
+// and an error.
+func (conf TypedConfig) GetDuration(k string, def time.Duration) (time.Duration, error) {
+	v, ok, err := conf.LookupDuration(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
+
+// GetList gets a property as a list or returns def if it is missing.
+// If the property cannot be converted GetList returns def and an error.
+func (conf TypedConfig) GetList(k string, def []interface{}) ([]interface{}, error) {
+	v, ok, err := conf.LookupList(k)
+	if !ok || nil != err {
+		return def, err
+	}
+	return v, nil
+}
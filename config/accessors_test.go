@@ -0,0 +1,120 @@
+/*
+ * accessors_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+func TestConfigAccessors(t *testing.T) {
+	conf, err := Read(strings.NewReader(
+		"name=app\nempty=\"\"\nflag\n[server]\nport=8080\nratio=0.5\n" +
+			"debug=false\ntimeout=5s\nhosts=a, b\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if v, err := conf.GetString("name", "x"); "app" != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, ok, err := conf.LookupString("empty"); "" != v || !ok || nil != err {
+		t.Error(v, ok, err)
+	}
+	if v, ok, err := conf.LookupString("missing"); "" != v || ok || nil != err {
+		t.Error(v, ok, err)
+	}
+	if v, err := conf.GetInt("server.port", 80); 8080 != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetInt("server.missing", 80); 80 != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetFloat("server.ratio", 0); 0.5 != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetBool("server.debug", true); false != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetBool("flag", false); true != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetDuration("server.timeout", 0); 5*time.Second != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetList("server.hosts", nil); !reflect.DeepEqual([]interface{}{"a", "b"}, v) ||
+		nil != err {
+		t.Error(v, err)
+	}
+
+	v, err := conf.GetInt("name", 42)
+	if 42 != v || nil == err || ErrConfig != errors.Attachment(err) ||
+		!strings.HasPrefix(err.Error(), "cannot convert name to integer") {
+		t.Error(v, err)
+	}
+	_, ok, err := conf.LookupDuration("server.port")
+	if !ok || nil == err {
+		t.Error(ok, err)
+	}
+}
+
+func TestTypedConfigAccessors(t *testing.T) {
+	conf, err := ReadTyped(strings.NewReader(
+		"name=app\nflag\n[server]\nport=8080\nratio=1\n" +
+			"timeout=\"5s\"\nnanos=1000\nzero=0\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	conf.Set("server.hosts", []interface{}{"a", "b"})
+
+	if v, err := conf.GetString("server.port", ""); "8080" != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetInt("server.port", 0); 8080 != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetFloat("server.ratio", 0); 1.0 != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetBool("flag", false); true != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetDuration("server.timeout", 0); 5*time.Second != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetDuration("server.nanos", time.Second); time.Second != v || nil == err ||
+		ErrConfig != errors.Attachment(err) {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetDuration("server.zero", time.Second); 0 != v || nil != err {
+		t.Error(v, err)
+	}
+	if v, err := conf.GetList("server.hosts", nil); 2 != len(v) || nil != err {
+		t.Error(v, err)
+	}
+	if v, ok, err := conf.LookupInt("missing.port"); 0 != v || ok || nil != err {
+		t.Error(v, ok, err)
+	}
+
+	if v, err := conf.GetBool("server.port", true); true != v || nil == err {
+		t.Error(v, err)
+	}
+	if _, err := conf.GetString("server.hosts", ""); nil == err ||
+		ErrConfig != errors.Attachment(err) {
+		t.Error(err)
+	}
+}
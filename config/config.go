@@ -35,12 +35,19 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
+
+	"github.com/billziss-gh/golib/errors"
 )
 
+const ErrConfig = "ErrConfig"
+
 type (
 	// Section is used to store a configuration section as string properties.
 	Section map[string]string
@@ -61,13 +68,17 @@ type (
 	TypedConfig map[string]TypedSection
 )
 
-// Get gets a property from the configuration.
-func (conf Config) Get(k string) string {
-	s := ""
+// splitName splits a property name of the form SECTION.PROPNAME.
+func splitName(k string) (string, string) {
 	if i := strings.LastIndex(k, "."); -1 != i {
-		s = k[:i]
-		k = k[i+1:]
+		return k[:i], k[i+1:]
 	}
+	return "", k
+}
+
+// Get gets a property from the configuration.
+func (conf Config) Get(k string) string {
+	s, k := splitName(k)
 	sect, ok := conf[s]
 	if !ok {
 		return ""
@@ -77,11 +88,7 @@ func (conf Config) Get(k string) string {
 
 // Set sets a property in the configuration.
 func (conf Config) Set(k string, v string) {
-	s := ""
-	if i := strings.LastIndex(k, "."); -1 != i {
-		s = k[:i]
-		k = k[i+1:]
-	}
+	s, k := splitName(k)
 	sect, ok := conf[s]
 	if !ok {
 		sect = Section{}
@@ -92,11 +99,7 @@ func (conf Config) Set(k string, v string) {
 
 // Delete deletes a property from the configuration.
 func (conf Config) Delete(k string) {
-	s := ""
-	if i := strings.LastIndex(k, "."); -1 != i {
-		s = k[:i]
-		k = k[i+1:]
-	}
+	s, k := splitName(k)
 	sect, ok := conf[s]
 	if !ok {
 		return
@@ -109,11 +112,7 @@ func (conf Config) Delete(k string) {
 
 // Get gets a property from the configuration.
 func (conf TypedConfig) Get(k string) interface{} {
-	s := ""
-	if i := strings.LastIndex(k, "."); -1 != i {
-		s = k[:i]
-		k = k[i+1:]
-	}
+	s, k := splitName(k)
 	sect, ok := conf[s]
 	if !ok {
 		return nil
@@ -123,11 +122,7 @@ func (conf TypedConfig) Get(k string) interface{} {
 
 // Set sets a property in the configuration.
 func (conf TypedConfig) Set(k string, v interface{}) {
-	s := ""
-	if i := strings.LastIndex(k, "."); -1 != i {
-		s = k[:i]
-		k = k[i+1:]
-	}
+	s, k := splitName(k)
 	sect, ok := conf[s]
 	if !ok {
 		sect = TypedSection{}
@@ -138,11 +133,7 @@ func (conf TypedConfig) Set(k string, v interface{}) {
 
 // Delete deletes a property from the configuration.
 func (conf TypedConfig) Delete(k string) {
-	s := ""
-	if i := strings.LastIndex(k, "."); -1 != i {
-		s = k[:i]
-		k = k[i+1:]
-	}
+	s, k := splitName(k)
 	sect, ok := conf[s]
 	if !ok {
 		return
@@ -174,7 +165,145 @@ type Dialect struct {
 	WriteEmptyKeys bool
 
 	// Strict determines whether parse errors should be reported.
+	// Parse errors are reported as an error whose cause is a *ParseError.
 	Strict bool
+
+	// Warning is called for every line that cannot be parsed when Strict
+	// is false.
+	Warning func(line ParseErrorLine)
+
+	// IncludeKey contains the property name used to include other files.
+	// The value of such a property is a file name or glob pattern; relative
+	// names are resolved relative to the directory of the including file.
+	// Included files start in the unnamed section. Includes are disabled
+	// when IncludeKey is empty.
+	IncludeKey string
+
+	// BackslashContinuation determines whether a value that ends in a
+	// backslash continues on the next line. The backslash is removed and
+	// the lines are joined with a newline. Multi-line values are written
+	// in this style (unless IndentContinuation is also set).
+	BackslashContinuation bool
+
+	// IndentContinuation determines whether indented lines that follow a
+	// property continue its value. The lines are joined with a newline.
+	// Multi-line values are written in this style.
+	IndentContinuation bool
+
+	// Lists determines whether list properties are supported by ReadTyped
+	// and WriteTyped. A list is stored as repeated properties whose names
+	// end in [] (e.g. name[]=value); each such property appends an element
+	// to the list. A property name[] without a value creates an empty list.
+	// Read does not interpret list properties.
+	Lists bool
+
+	// Subsections determines whether git-config style section headers are
+	// supported. A header of the form [section "subsection"] is read as
+	// the section "section.subsection"; section names that contain a dot
+	// are written in this form.
+	Subsections bool
+
+	// Interpolate determines whether references of the form ${SECTION.PROPNAME}
+	// and ${env:NAME} within values are resolved during reading.
+	// See Config.Interpolate.
+	Interpolate bool
+
+	// Key returns the AES key (16, 24 or 32 bytes long) used to decrypt
+	// and encrypt values. It is called at most once per read or write and
+	// only when encrypted values are present. Values are only decrypted
+	// when Key is set. See keyring.KeyFunc for a
+	// function that fetches the key from the system keyring.
+	Key func() ([]byte, error)
+
+	// ReadDurations determines whether ReadTyped infers time.Duration values
+	// from unquoted values such as 30s or 1h30m.
+	ReadDurations bool
+
+	// ReadSizes determines whether ReadTyped infers Size values from
+	// unquoted values with a size unit such as 512MiB or 10KB. Durations
+	// take precedence over sizes (e.g. 5m is 5 minutes).
+	ReadSizes bool
+
+	// ReadTimes determines whether ReadTyped infers time.Time values from
+	// unquoted RFC 3339 timestamps such as 2006-01-02T15:04:05Z.
+	ReadTimes bool
+
+	// IgnoreCase determines whether section and property names are matched
+	// case-insensitively during reading, as in Windows INI files. Names that
+	// differ only in case (as determined by strings.ToLower) refer to the
+	// same section or property; the casing of the first occurrence is
+	// preserved (and used by Write). Use FoldConfig and FoldTypedConfig to
+	// access the resulting configurations.
+	IgnoreCase bool
+
+	// Duplicates determines how properties that appear more than once in
+	// the same file are handled. Duplicates are reported through Warning
+	// (except when they are collected into lists); with DuplicateError they
+	// are reported as an error whose cause is a *ParseError. Properties in
+	// included files are not considered duplicates of properties in the
+	// including file.
+	Duplicates DuplicatePolicy
+
+	// Encrypted lists the properties that are encrypted during writing.
+	// Entries have the form SECTION.PROPNAME or SECTION.* for all the
+	// properties of a section. Encrypted values are written as enc:DATA
+	// where DATA is the base64 encoded AES-GCM ciphertext of the value.
+	//
+	// When Key is set, values of the form enc:DATA are decrypted during
+	// reading regardless of Encrypted (a quoted "enc:..." value is not
+	// decrypted); otherwise they are read as they are. Documents
+	// do not decrypt or encrypt values; they preserve them as they are.
+	Encrypted []string
+}
+
+// DuplicatePolicy determines how duplicate properties are handled.
+type DuplicatePolicy int
+
+const (
+	// DuplicateLastWins keeps the last value of a duplicate property.
+	DuplicateLastWins DuplicatePolicy = iota
+
+	// DuplicateFirstWins keeps the first value of a duplicate property.
+	DuplicateFirstWins
+
+	// DuplicateError reports duplicate properties and sections as errors.
+	DuplicateError
+
+	// DuplicateList collects the values of a duplicate property into a
+	// list for ReadTyped; Read joins the values with commas.
+	DuplicateList
+)
+
+// ParseErrorLine describes a line that could not be parsed.
+type ParseErrorLine struct {
+	// File is the name of the file that contains the line (if known).
+	File string
+
+	// Line is the line number (starting at 1).
+	Line int
+
+	// Text is the raw text of the line.
+	Text string
+
+	// Reason describes why the line could not be parsed.
+	Reason string
+}
+
+// ParseError lists the lines that could not be parsed.
+type ParseError struct {
+	Lines []ParseErrorLine
+}
+
+func (err *ParseError) Error() string {
+	parts := make([]string, len(err.Lines))
+	for i, l := range err.Lines {
+		if "" == l.File {
+			parts[i] = fmt.Sprintf("line %d: %s", l.Line, l.Reason)
+		} else {
+			parts[i] = fmt.Sprintf("%s:%d: %s", l.File, l.Line, l.Reason)
+		}
+	}
+	return strings.Join(parts, "; ")
 }
 
 // DefaultDialect contains the default configuration dialect.
@@ -187,155 +316,475 @@ var DefaultDialect = &Dialect{
 	Strict:         false,
 }
 
-func (dialect *Dialect) ReadFunc(
-	reader io.Reader, fn func(sect, name string, valu interface{})) error {
-	scan := bufio.NewScanner(reader)
-	sect := ""
-	errc := 0
-	for scan.Scan() {
-		line := scan.Text()
-		line = strings.TrimSpace(line)
-		if 0 == len(line) {
-			continue
+const (
+	itemBlank = iota
+	itemComment
+	itemSection
+	itemProperty
+	itemError
+)
+
+// item is a single parsed line of a configuration file.
+type item struct {
+	kind int
+	line int
+	raw  string
+	sect string
+	name string
+	valu interface{}
+	voff int    // offset of the value in raw; -1 if there is no assignment
+	errs string // reason for itemError
+}
+
+// parser splits a configuration file into items.
+type parser struct {
+	dialect *Dialect
+	scan    *bufio.Scanner
+	sect    string
+	line    int
+	unread  *string
+}
+
+func (dialect *Dialect) newParser(reader io.Reader) *parser {
+	return &parser{dialect: dialect, scan: bufio.NewScanner(reader)}
+}
+
+// parseSection parses a section header such as [section] or (when
+// Subsections is set) [section "subsection"].
+func (dialect *Dialect) parseSection(line string) (string, bool) {
+	if dialect.Subsections {
+		if i := strings.IndexAny(line, "\"]"); -1 != i && '"' == line[i] {
+			sub, rest := unquote(line[i:])
+			rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+			if 0 == len(rest) || ']' != rest[0] {
+				return "", false
+			}
+			return strings.TrimSpace(line[1:i]) + "." + sub, true
 		}
+	}
+	if i := strings.IndexByte(line, ']'); -1 != i {
+		return line[1:i], true
+	}
+	return "", false
+}
 
-		// comment
-		if i := strings.IndexByte(dialect.CommentChars, line[0]); -1 != i {
-			continue
+// formatSection formats a section header for writing.
+func (dialect *Dialect) formatSection(sect string) string {
+	if dialect.Subsections {
+		if i := strings.IndexByte(sect, '.'); -1 != i {
+			return "[" + sect[:i] + " " + quote(sect[i+1:], true) + "]"
 		}
+	}
+	return "[" + sect + "]"
+}
 
-		// section name
-		if '[' == line[0] {
-			if i := strings.IndexByte(line, ']'); -1 != i {
-				sect = line[1:i]
-			} else {
-				errc++
-			}
-			continue
+func (p *parser) readLine() (string, bool) {
+	if nil != p.unread {
+		line := *p.unread
+		p.unread = nil
+		p.line++
+		return line, true
+	}
+	if !p.scan.Scan() {
+		return "", false
+	}
+	p.line++
+	return p.scan.Text(), true
+}
+
+func (p *parser) unreadLine(line string) {
+	p.unread = &line
+	p.line--
+}
+
+func (p *parser) next() (it item, ok bool) {
+	it.raw, ok = p.readLine()
+	if !ok {
+		return
+	}
+
+	it.line = p.line
+	it.voff = -1
+
+	line := strings.TrimSpace(it.raw)
+	if 0 == len(line) {
+		it.kind = itemBlank
+		return
+	}
+	base := len(it.raw) - len(strings.TrimLeftFunc(it.raw, unicode.IsSpace)) + len(line)
+
+	// comment
+	if i := strings.IndexByte(p.dialect.CommentChars, line[0]); -1 != i {
+		it.kind = itemComment
+		return
+	}
+
+	// section name
+	if '[' == line[0] {
+		if sect, ok := p.dialect.parseSection(line); ok {
+			p.sect = sect
+			it.kind = itemSection
+			it.sect = p.sect
+		} else {
+			it.kind = itemError
+			it.errs = "unterminated section header"
 		}
+		return
+	}
 
-		name := ""
-		valu := (interface{})(nil)
+	it.kind = itemProperty
+	it.sect = p.sect
 
-		// name
-		if '"' == line[0] {
-			name, line = unquote(line)
-			line = strings.TrimLeftFunc(line, unicode.IsSpace)
-			if 0 == len(line) {
-			} else if i := strings.IndexByte(dialect.AssignChars, line[0]); -1 != i {
-				line = strings.TrimLeftFunc(line[i+1:], unicode.IsSpace)
-				valu = ""
-			} else {
-				errc++
-				continue
-			}
+	// name
+	if '"' == line[0] {
+		it.name, line = unquote(line)
+		line = strings.TrimLeftFunc(line, unicode.IsSpace)
+		if 0 == len(line) {
+		} else if i := strings.IndexByte(p.dialect.AssignChars, line[0]); -1 != i {
+			line = strings.TrimLeftFunc(line[1:], unicode.IsSpace)
+			it.valu = ""
 		} else {
-			if i := strings.IndexAny(line, dialect.AssignChars); -1 != i {
-				name = strings.TrimRightFunc(line[:i], unicode.IsSpace)
-				line = strings.TrimLeftFunc(line[i+1:], unicode.IsSpace)
-				valu = ""
-			} else {
-				name = line
-				line = ""
-			}
+			it.kind = itemError
+			it.errs = "missing assignment after quoted name"
+			return
 		}
+	} else {
+		if i := strings.IndexAny(line, p.dialect.AssignChars); -1 != i {
+			it.name = strings.TrimRightFunc(line[:i], unicode.IsSpace)
+			line = strings.TrimLeftFunc(line[i+1:], unicode.IsSpace)
+			it.valu = ""
+		} else {
+			it.name = line
+			line = ""
+		}
+	}
+
+	// value
+	if nil == it.valu && !p.dialect.ReadEmptyKeys &&
+		!(p.dialect.Lists && strings.HasSuffix(it.name, "[]")) {
+		it.kind = itemError
+		it.errs = "missing value"
+		return
+	}
+	if nil != it.valu {
+		it.voff = base - len(line)
+		if 0 == len(line) || '"' != line[0] {
+			line = p.continuation(&it, line)
+		}
+	}
+	if 0 != len(line) {
+		it.valu = line
+	}
 
-		// value
-		if nil == valu && !dialect.ReadEmptyKeys {
-			errc++
-			continue
+	return
+}
+
+// continuation reads the continuation lines of an unquoted value.
+// The lines of a multi-line value are joined with newlines.
+func (p *parser) continuation(it *item, valu string) string {
+	join := func(valu, line string) string {
+		if 0 == len(valu) {
+			return line
+		}
+		return valu + "\n" + line
+	}
+
+	if p.dialect.BackslashContinuation {
+		for 0 < len(valu) && '\\' == valu[len(valu)-1] {
+			valu = strings.TrimRightFunc(valu[:len(valu)-1], unicode.IsSpace)
+			raw, ok := p.readLine()
+			if !ok {
+				break
+			}
+			it.raw += "\n" + raw
+			valu = join(valu, strings.TrimSpace(raw))
 		}
-		if 0 != len(line) {
-			valu = line
+	}
+
+	if p.dialect.IndentContinuation {
+		for {
+			raw, ok := p.readLine()
+			if !ok {
+				break
+			}
+			line := strings.TrimSpace(raw)
+			if 0 == len(line) || (' ' != raw[0] && '\t' != raw[0]) {
+				p.unreadLine(raw)
+				break
+			}
+			it.raw += "\n" + raw
+			valu = join(valu, line)
 		}
+	}
+
+	return valu
+}
+
+func (p *parser) err() error {
+	return p.scan.Err()
+}
 
+func (dialect *Dialect) ReadFunc(
+	reader io.Reader, fn func(sect, name string, valu interface{})) error {
+	return dialect.readFunc(reader, "", func(file, sect, name string, valu interface{}) {
 		fn(sect, name, valu)
+	})
+}
+
+// readFunc reads properties from the supplied reader. The path parameter
+// names the file being read (if any) and is used to resolve includes.
+func (dialect *Dialect) readFunc(reader io.Reader, path string,
+	fn func(file, sect, name string, valu interface{})) error {
+	state := &readState{fn: fn}
+	if "" != path {
+		abs, err := filepath.Abs(path)
+		if nil != err {
+			return err
+		}
+		state.stack = append(state.stack, abs)
 	}
 
-	if err := scan.Err(); nil != err {
+	err := dialect.readItems(state, reader, path)
+	if nil != err {
 		return err
 	}
 
-	if 0 != errc && dialect.Strict {
-		return fmt.Errorf("unable to parse %d lines", errc)
+	if 0 != len(state.errs) {
+		return errors.New(
+			fmt.Sprintf("unable to parse %d lines", len(state.errs)),
+			&ParseError{state.errs}, ErrConfig)
 	}
 
 	return nil
 }
 
+func (dialect *Dialect) readItems(state *readState, reader io.Reader, path string) error {
+	sects := map[string]int{}
+	props := map[string]int{}
+	duplicate := func(it *item, reason string) {
+		l := ParseErrorLine{File: path, Line: it.line, Text: it.raw, Reason: reason}
+		if DuplicateError == dialect.Duplicates {
+			state.errs = append(state.errs, l)
+		} else if nil != dialect.Warning {
+			dialect.Warning(l)
+		}
+	}
+
+	p := dialect.newParser(reader)
+	for {
+		it, ok := p.next()
+		if !ok {
+			break
+		}
+
+		switch it.kind {
+		case itemSection:
+			if l, ok := sects[dialect.foldName(it.sect)]; ok {
+				duplicate(&it, fmt.Sprintf("duplicate section [%s] (first at line %d)", it.sect, l))
+			} else {
+				sects[dialect.foldName(it.sect)] = it.line
+			}
+		case itemProperty:
+			if v, ok := it.valu.(string); ok && nil != dialect.Key &&
+				strings.HasPrefix(v, encPrefix) {
+				v, err := dialect.decryptValue(state, v)
+				if nil != err {
+					return errors.New(
+						fmt.Sprintf("cannot decrypt %s", propertyName(it.sect, it.name)),
+						err, ErrConfig)
+				}
+				it.valu = v
+			}
+			if "" != dialect.IncludeKey && dialect.IncludeKey == it.name {
+				err := dialect.include(state, path, itemString(it.valu))
+				if nil != err {
+					return err
+				}
+				continue
+			}
+			if !dialect.Lists || !strings.HasSuffix(it.name, "[]") {
+				k := dialect.foldName(it.sect) + "\x00" + dialect.foldName(it.name)
+				if l, ok := props[k]; !ok {
+					props[k] = it.line
+				} else if DuplicateList != dialect.Duplicates {
+					duplicate(&it, fmt.Sprintf("duplicate property %s (first at line %d)",
+						propertyName(it.sect, it.name), l))
+					if DuplicateLastWins != dialect.Duplicates {
+						continue
+					}
+				}
+			}
+			state.fn(path, it.sect, it.name, it.valu)
+		case itemError:
+			l := ParseErrorLine{File: path, Line: it.line, Text: it.raw, Reason: it.errs}
+			if dialect.Strict {
+				state.errs = append(state.errs, l)
+			} else if nil != dialect.Warning {
+				dialect.Warning(l)
+			}
+		}
+	}
+
+	return p.err()
+}
+
+// typedValue converts a raw value as read by ReadFunc to a typed value.
+func (dialect *Dialect) typedValue(valu interface{}) interface{} {
+	if nil == valu {
+		return true
+	}
+
+	s := valu.(string)
+	if 0 < len(s) && '"' == s[0] {
+		s, _ = unquote(s)
+		return s
+	}
+	return dialect.inferValue(s)
+}
+
+// inferValue infers the type of an unquoted value.
+func (dialect *Dialect) inferValue(s string) interface{} {
+	if v, err := strconv.ParseInt(s, 0, 64); nil == err {
+		return v
+	}
+	if v, err := strconv.ParseFloat(s, 64); nil == err {
+		return v
+	}
+	if v, err := strconv.ParseBool(s); nil == err {
+		return v
+	}
+	if dialect.ReadDurations {
+		if v, err := time.ParseDuration(s); nil == err {
+			return v
+		}
+	}
+	if dialect.ReadSizes {
+		if v, err := parseSize(s, true); nil == err {
+			return v
+		}
+	}
+	if dialect.ReadTimes {
+		if v, err := time.Parse(time.RFC3339Nano, s); nil == err {
+			return v
+		}
+	}
+	return s
+}
+
 // Read reads a configuration from the supplied reader.
 func (dialect *Dialect) Read(reader io.Reader) (Config, error) {
+	return dialect.read(reader, "")
+}
+
+func (dialect *Dialect) read(reader io.Reader, path string) (Config, error) {
 	conf := Config{}
+	seen := map[string]bool{}
+	names := dialect.newNameFolder()
 
-	err := dialect.ReadFunc(reader, func(sect, name string, valu interface{}) {
-		v, _ := valu.(string)
-		if 0 < len(v) && '"' == v[0] {
-			v, _ = unquote(v)
-		}
-		if smap, ok := conf[sect]; ok {
-			smap[name] = v
-		} else {
+	err := dialect.readFunc(reader, path, func(file, sect, name string, valu interface{}) {
+		sect, name = names.fold(sect, name)
+		v := itemString(valu)
+		smap, ok := conf[sect]
+		if !ok {
 			smap = Section{}
 			conf[sect] = smap
+		}
+		if k := file + "\x00" + sect + "\x00" + name; !seen[k] ||
+			DuplicateList != dialect.Duplicates {
 			smap[name] = v
+			seen[k] = true
+		} else {
+			smap[name] += ", " + v
 		}
 	})
 	if nil != err {
 		return nil, err
 	}
 
+	if dialect.Interpolate {
+		err = conf.Interpolate()
+		if nil != err {
+			return nil, err
+		}
+	}
+
 	return conf, nil
 }
 
 // ReadTyped reads a typed configuration from the supplied reader.
 func (dialect *Dialect) ReadTyped(reader io.Reader) (TypedConfig, error) {
+	return dialect.readTyped(reader, "", nil)
+}
+
+func (dialect *Dialect) readTyped(reader io.Reader, path string,
+	origin map[string]string) (TypedConfig, error) {
 	conf := TypedConfig{}
+	seen := map[string]bool{}
+	dups := map[string]bool{}
+	names := dialect.newNameFolder()
 
-	err := dialect.ReadFunc(reader, func(sect, name string, valu interface{}) {
-		var v interface{}
-		if nil == valu {
-			v = true
-		} else {
-			s := valu.(string)
-			if 0 < len(s) && '"' == s[0] {
-				s, _ = unquote(s)
-				v = s
-				goto done
-			}
-			var err error
-			v, err = strconv.ParseInt(s, 0, 64)
-			if nil == err {
-				goto done
-			}
-			v, err = strconv.ParseFloat(s, 64)
-			if nil == err {
-				goto done
-			}
-			v, err = strconv.ParseBool(s)
-			if nil == err {
-				goto done
-			}
-			v = s
+	err := dialect.readFunc(reader, path, func(file, sect, name string, valu interface{}) {
+		sect, name = names.fold(sect, name)
+		// defer typing of values until after interpolation
+		v := valu
+		if !dialect.Interpolate {
+			v = dialect.typedValue(valu)
 		}
-	done:
-		if smap, ok := conf[sect]; ok {
-			smap[name] = v
-		} else {
+		smap, ok := conf[sect]
+		if !ok {
 			smap = TypedSection{}
 			conf[sect] = smap
+		}
+		if dialect.Lists && strings.HasSuffix(name, "[]") {
+			name = name[:len(name)-2]
+			list, _ := smap[name].([]interface{})
+			if nil == valu {
+				list = []interface{}{}
+			} else {
+				list = append(list, v)
+			}
+			smap[name] = list
+		} else if k := file + "\x00" + sect + "\x00" + name; !seen[k] ||
+			DuplicateList != dialect.Duplicates {
 			smap[name] = v
+			seen[k] = true
+		} else {
+			// collect duplicates; raw empty keys must be strings within lists
+			if nil == v {
+				v = "true"
+			}
+			if !dups[k] {
+				old := smap[name]
+				if nil == old {
+					old = "true"
+				}
+				smap[name] = []interface{}{old}
+				dups[k] = true
+			}
+			smap[name] = append(smap[name].([]interface{}), v)
+		}
+		if nil != origin {
+			origin[propertyName(sect, name)] = file
 		}
 	})
 	if nil != err {
 		return nil, err
 	}
 
+	if dialect.Interpolate {
+		err = dialect.interpolateRaw(conf)
+		if nil != err {
+			return nil, err
+		}
+	}
+
 	return conf, nil
 }
 
 // Write writes a configuration to the supplied writer.
 func (dialect *Dialect) Write(writer io.Writer, conf Config) error {
 	bufw := bufio.NewWriter(writer)
+	enc := dialect.newEncrypter()
 
 	sects := make([]string, 0, len(conf))
 	for sect := range conf {
@@ -345,9 +794,7 @@ func (dialect *Dialect) Write(writer io.Writer, conf Config) error {
 
 	for _, sect := range sects {
 		if "" != sect {
-			bufw.WriteByte('[')
-			bufw.WriteString(sect)
-			bufw.WriteByte(']')
+			bufw.WriteString(dialect.formatSection(sect))
 			bufw.WriteByte('\n')
 		}
 
@@ -360,8 +807,18 @@ func (dialect *Dialect) Write(writer io.Writer, conf Config) error {
 
 		for _, name := range names {
 			valu := smap[name]
+			if dialect.encrypted(sect, name) {
+				var err error
+				valu, err = enc.encrypt(quote(valu, false))
+				if nil != err {
+					return errors.New(
+						fmt.Sprintf("cannot encrypt %s", propertyName(sect, name)),
+						err, ErrConfig)
+				}
+			} else {
+				valu = dialect.formatValue(valu, false)
+			}
 			name = quote(name, false)
-			valu = quote(valu, false)
 			bufw.WriteString(name)
 			bufw.WriteByte(dialect.AssignChars[0])
 			bufw.WriteString(valu)
@@ -377,6 +834,7 @@ func (dialect *Dialect) Write(writer io.Writer, conf Config) error {
 // WriteTyped writes a typed configuration to the supplied writer.
 func (dialect *Dialect) WriteTyped(writer io.Writer, conf TypedConfig) error {
 	bufw := bufio.NewWriter(writer)
+	enc := dialect.newEncrypter()
 
 	sects := make([]string, 0, len(conf))
 	for sect := range conf {
@@ -386,9 +844,7 @@ func (dialect *Dialect) WriteTyped(writer io.Writer, conf TypedConfig) error {
 
 	for _, sect := range sects {
 		if "" != sect {
-			bufw.WriteByte('[')
-			bufw.WriteString(sect)
-			bufw.WriteByte(']')
+			bufw.WriteString(dialect.formatSection(sect))
 			bufw.WriteByte('\n')
 		}
 
@@ -400,47 +856,132 @@ func (dialect *Dialect) WriteTyped(writer io.Writer, conf TypedConfig) error {
 		sort.Sort(sort.StringSlice(names))
 
 		for _, name := range names {
-			valu := smap[name]
-			name = quote(name, false)
-			q := ""
-			switch v := valu.(type) {
-			case string:
-				q = quote(v, true)
-			case bool:
-				if v && dialect.WriteEmptyKeys {
-					bufw.WriteString(name)
-					continue
-				}
-				q = strconv.FormatBool(v)
-			case int:
-				q = strconv.FormatInt(int64(v), 10)
-			case int32:
-				q = strconv.FormatInt(int64(v), 10)
-			case int64:
-				q = strconv.FormatInt(v, 10)
-			case uint:
-				q = strconv.FormatUint(uint64(v), 10)
-			case uint32:
-				q = strconv.FormatUint(uint64(v), 10)
-			case uint64:
-				q = strconv.FormatUint(v, 10)
-			case float32:
-				q = formatFloat(float64(v), 32)
-			case float64:
-				q = formatFloat(v, 64)
-			default:
-				q = quote(fmt.Sprintf("%v", v), false)
+			var err error
+			if dialect.encrypted(sect, name) {
+				err = dialect.writeTypedProperty(bufw, name, smap[name], enc)
+			} else {
+				err = dialect.writeTypedProperty(bufw, name, smap[name], nil)
+			}
+			if nil != err {
+				return errors.New(
+					fmt.Sprintf("cannot encrypt %s", propertyName(sect, name)),
+					err, ErrConfig)
+			}
+		}
+
+		bufw.WriteByte('\n')
+	}
+
+	return bufw.Flush()
+}
+
+// formatValue formats a string value for writing. Multi-line values are
+// written using continuation lines if the dialect supports them and the
+// value can be read back unchanged; otherwise they are quoted.
+func (dialect *Dialect) formatValue(s string, force bool) string {
+	if (!dialect.BackslashContinuation && !dialect.IndentContinuation) ||
+		-1 == strings.IndexByte(s, '\n') || '"' == s[0] {
+		return quote(s, force)
+	}
+
+	lines := strings.Split(s, "\n")
+	for _, l := range lines {
+		if 0 == len(l) || l != strings.TrimSpace(l) ||
+			(!dialect.IndentContinuation && '\\' == l[len(l)-1]) {
+			return quote(s, force)
+		}
+	}
+
+	if dialect.IndentContinuation {
+		return "\n    " + strings.Join(lines, "\n    ")
+	}
+	return "\\\n" + strings.Join(lines, "\\\n")
+}
+
+// writeTypedProperty writes a typed property (or list property).
+// Values are encrypted when enc is not nil.
+func (dialect *Dialect) writeTypedProperty(bufw *bufio.Writer,
+	name string, valu interface{}, enc *encrypter) error {
+	format := dialect.formatTypedValue
+	if nil != enc {
+		format = func(valu interface{}) string {
+			if s, ok := valu.(string); ok {
+				return quote(s, true)
+			}
+			return dialect.formatTypedValue(valu)
+		}
+	}
+
+	if list, ok := listValue(valu); ok && dialect.Lists {
+		if q := quote(name, false); q == name {
+			name += "[]"
+		} else {
+			name = quote(name+"[]", true)
+		}
+		if 0 == len(list) {
+			bufw.WriteString(name)
+			bufw.WriteByte('\n')
+		}
+		for _, elem := range list {
+			v, err := enc.encrypt(format(elem))
+			if nil != err {
+				return err
 			}
 			bufw.WriteString(name)
 			bufw.WriteByte(dialect.AssignChars[0])
-			bufw.WriteString(q)
+			bufw.WriteString(v)
 			bufw.WriteByte('\n')
 		}
-
+		return nil
+	}
+	name = quote(name, false)
+	if v, ok := valu.(bool); ok && v && dialect.WriteEmptyKeys && nil == enc {
+		bufw.WriteString(name)
 		bufw.WriteByte('\n')
+		return nil
+	}
+	v, err := enc.encrypt(format(valu))
+	if nil != err {
+		return err
+	}
+	bufw.WriteString(name)
+	bufw.WriteByte(dialect.AssignChars[0])
+	bufw.WriteString(v)
+	bufw.WriteByte('\n')
+	return nil
+}
+
+// formatTypedValue formats a typed value for writing.
+func (dialect *Dialect) formatTypedValue(valu interface{}) string {
+	switch v := valu.(type) {
+	case string:
+		return dialect.formatValue(v, true)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return formatTyped(v)
+	default:
+		return quote(formatTyped(v), false)
 	}
+}
 
-	return bufw.Flush()
+// listValue determines whether a typed value is a list.
+func listValue(valu interface{}) ([]interface{}, bool) {
+	if list, ok := valu.([]interface{}); ok {
+		return list, true
+	}
+	if nil == valu {
+		return nil, false
+	}
+	rv := reflect.ValueOf(valu)
+	if reflect.Slice != rv.Kind() || reflect.Uint8 == rv.Type().Elem().Kind() {
+		return nil, false
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, true
 }
 
 func quote(s string, force bool) string {
@@ -488,22 +1029,19 @@ func unquote(s string) (string, string) {
 	buf := bytes.Buffer{}
 	for i := 1; len(s) > i; i++ {
 		switch c := s[i]; c {
-		case 'r':
-			if '\\' == s[i-1] {
-				c = '\r'
+		case '\\':
+			if i++; len(s) == i {
+				break
 			}
-			buf.WriteByte(c)
-		case 'n':
-			if '\\' == s[i-1] {
+			switch c = s[i]; c {
+			case 'r':
+				c = '\r'
+			case 'n':
 				c = '\n'
 			}
 			buf.WriteByte(c)
-		case '\\':
 		case '"':
-			if '\\' != s[i-1] {
-				return buf.String(), s[i+1:]
-			}
-			fallthrough
+			return buf.String(), s[i+1:]
 		default:
 			buf.WriteByte(c)
 		}
@@ -536,6 +1074,18 @@ func ReadTyped(reader io.Reader) (TypedConfig, error) {
 	return DefaultDialect.ReadTyped(reader)
 }
 
+// ReadFile reads a configuration from the named file
+// using the default dialect.
+func ReadFile(path string) (Config, error) {
+	return DefaultDialect.ReadFile(path)
+}
+
+// ReadTypedFile reads a typed configuration from the named file
+// using the default dialect.
+func ReadTypedFile(path string) (TypedConfig, error) {
+	return DefaultDialect.ReadTypedFile(path)
+}
+
 // Write writes a configuration to the supplied writer
 // using the default dialect.
 func Write(writer io.Writer, conf Config) error {
@@ -13,10 +13,14 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/billziss-gh/golib/errors"
 )
 
 func TestInternals(t *testing.T) {
@@ -47,6 +51,11 @@ func TestInternals(t *testing.T) {
 		t.Error()
 	}
 
+	s, rest = unquote(`"a\\b\\""def"`)
+	if `a\b\` != s || `"def"` != rest {
+		t.Error()
+	}
+
 	s, rest = unquote(`"abc`)
 	if `abc` != s || `` != rest {
 		t.Error()
@@ -262,3 +271,282 @@ func TestTypedGetSetDelete(t *testing.T) {
 		t.Error()
 	}
 }
+
+func TestParseError(t *testing.T) {
+	text := `[sect
+"quoted" value
+ok=1
+empty
+`
+	var dialect = &Dialect{
+		AssignChars:   "=:",
+		CommentChars:  ";#",
+		ReadEmptyKeys: false,
+		Strict:        true,
+	}
+
+	_, err := dialect.Read(strings.NewReader(text))
+	if nil == err {
+		t.Fatal()
+	}
+	if !errors.HasAttachment(err, ErrConfig) {
+		t.Error()
+	}
+	perr, ok := errors.Cause(err).(*ParseError)
+	if !ok {
+		t.Fatal()
+	}
+	expected := []ParseErrorLine{
+		{"", 1, "[sect", "unterminated section header"},
+		{"", 2, `"quoted" value`, "missing assignment after quoted name"},
+		{"", 4, "empty", "missing value"},
+	}
+	if !reflect.DeepEqual(expected, perr.Lines) {
+		t.Error(perr.Lines)
+	}
+	if "unable to parse 3 lines; line 1: unterminated section header; "+
+		"line 2: missing assignment after quoted name; line 4: missing value" != err.Error() {
+		t.Error(err)
+	}
+
+	var warnings []ParseErrorLine
+	dialect.Strict = false
+	dialect.Warning = func(line ParseErrorLine) {
+		warnings = append(warnings, line)
+	}
+	conf, err := dialect.Read(strings.NewReader(text))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, warnings) {
+		t.Error(warnings)
+	}
+	if "1" != conf.Get("ok") {
+		t.Error()
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	var dialect = &Dialect{
+		AssignChars:           "=:",
+		CommentChars:          ";#",
+		ReadEmptyKeys:         true,
+		BackslashContinuation: true,
+	}
+
+	conf, err := dialect.Read(strings.NewReader(`a=line1 \
+  line2\
+line3
+b=single
+c=\
+`))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "line1\nline2\nline3" != conf.Get("a") || "single" != conf.Get("b") ||
+		"" != conf.Get("c") {
+		t.Error(conf)
+	}
+
+	dialect.BackslashContinuation = false
+	dialect.IndentContinuation = true
+	conf, err = dialect.Read(strings.NewReader(`[sect]
+cert =
+    -----BEGIN-----
+    abcd
+    -----END-----
+script = echo 1
+	echo 2
+
+after = value
+`))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "-----BEGIN-----\nabcd\n-----END-----" != conf.Get("sect.cert") ||
+		"echo 1\necho 2" != conf.Get("sect.script") ||
+		"value" != conf.Get("sect.after") {
+		t.Error(conf)
+	}
+}
+
+func TestContinuationRoundTrip(t *testing.T) {
+	conf := Config{}
+	conf.Set("sect.multi", "line1\nline2 with spaces\nline3\\")
+	conf.Set("sect.unsafe", "line1\n  indented")
+	conf.Set("sect.empty", "line1\n\nline3")
+	conf.Set("sect.single", "value")
+
+	tconf := TypedConfig{}
+	tconf.Set("sect.multi", "1\n2")
+	tconf.Set("sect.int", int64(1))
+
+	for _, dialect := range []*Dialect{
+		{AssignChars: "=", CommentChars: "#", BackslashContinuation: true},
+		{AssignChars: "=", CommentChars: "#", IndentContinuation: true},
+	} {
+		var buf strings.Builder
+		err := dialect.Write(&buf, conf)
+		if nil != err {
+			t.Fatal(err)
+		}
+		iconf, err := dialect.Read(strings.NewReader(buf.String()))
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(conf, iconf) {
+			t.Error(buf.String())
+		}
+		if dialect.IndentContinuation &&
+			!strings.Contains(buf.String(), "multi=\n    line1\n    line2 with spaces\n") {
+			t.Error(buf.String())
+		}
+
+		buf.Reset()
+		err = dialect.WriteTyped(&buf, tconf)
+		if nil != err {
+			t.Fatal(err)
+		}
+		itconf, err := dialect.ReadTyped(strings.NewReader(buf.String()))
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tconf, itconf) {
+			t.Error(buf.String())
+		}
+	}
+}
+
+func TestLists(t *testing.T) {
+	var dialect = &Dialect{
+		AssignChars:   "=:",
+		CommentChars:  ";#",
+		ReadEmptyKeys: false,
+		Lists:         true,
+	}
+
+	conf, err := dialect.ReadTyped(strings.NewReader(`
+name[]=a
+name[]="b c"
+name[]=3
+name[]=true
+reset[]=x
+reset[]
+empty[]
+`))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]interface{}{"a", "b c", int64(3), true}, conf.Get("name")) ||
+		!reflect.DeepEqual([]interface{}{}, conf.Get("reset")) ||
+		!reflect.DeepEqual([]interface{}{}, conf.Get("empty")) {
+		t.Error(conf)
+	}
+
+	tconf := TypedConfig{}
+	tconf.Set("sect.list", []interface{}{"a", "true", int64(1), 1.5, false, "x\ny"})
+	tconf.Set("sect.empty", []interface{}{})
+	tconf.Set("sect.long name", []interface{}{"v"})
+	tconf.Set("sect.scalar", "s")
+
+	var buf strings.Builder
+	err = dialect.WriteTyped(&buf, tconf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "list[]=\"a\"\nlist[]=\"true\"\nlist[]=1\n") ||
+		!strings.Contains(buf.String(), "\nempty[]\n") {
+		t.Error(buf.String())
+	}
+	iconf, err := dialect.ReadTyped(strings.NewReader(buf.String()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tconf, iconf) {
+		t.Error(iconf)
+	}
+
+	tconf = TypedConfig{}
+	tconf.Set("strings", []string{"a", "b"})
+	buf.Reset()
+	dialect.WriteTyped(&buf, tconf)
+	iconf, err = dialect.ReadTyped(strings.NewReader(buf.String()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]interface{}{"a", "b"}, iconf.Get("strings")) {
+		t.Error(iconf)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	const s = "a=1\n[s]\nb=x\nb=y\nflag\n[s]\nb=z\n"
+
+	var warnings []string
+	dialect := *DefaultDialect
+	dialect.Warning = func(l ParseErrorLine) {
+		warnings = append(warnings, fmt.Sprintf("%d: %s", l.Line, l.Reason))
+	}
+
+	conf, err := dialect.Read(strings.NewReader(s))
+	if nil != err || "z" != conf.Get("s.b") {
+		t.Error(conf, err)
+	}
+	expected := []string{
+		"4: duplicate property s.b (first at line 3)",
+		"6: duplicate section [s] (first at line 2)",
+		"7: duplicate property s.b (first at line 3)",
+	}
+	if !reflect.DeepEqual(expected, warnings) {
+		t.Error(warnings)
+	}
+
+	warnings = nil
+	dialect.Duplicates = DuplicateFirstWins
+	conf, err = dialect.Read(strings.NewReader(s))
+	if nil != err || "x" != conf.Get("s.b") || 3 != len(warnings) {
+		t.Error(conf, err, warnings)
+	}
+
+	warnings = nil
+	dialect.Duplicates = DuplicateList
+	conf, err = dialect.Read(strings.NewReader(s))
+	if nil != err || "x, y, z" != conf.Get("s.b") || 1 != len(warnings) {
+		t.Error(conf, err, warnings)
+	}
+	tconf, err := dialect.ReadTyped(strings.NewReader(s + "b=1\n"))
+	if nil != err || !reflect.DeepEqual([]interface{}{"x", "y", "z", int64(1)}, tconf.Get("s.b")) ||
+		int64(1) != tconf.Get("a") || true != tconf.Get("s.flag") {
+		t.Error(tconf, err)
+	}
+
+	dialect.Interpolate = true
+	tconf, err = dialect.ReadTyped(strings.NewReader("v=1\nflag\nflag\nl=${v}\nl=\"x\"\n"))
+	if nil != err || !reflect.DeepEqual([]interface{}{true, true}, tconf.Get("flag")) ||
+		!reflect.DeepEqual([]interface{}{int64(1), "x"}, tconf.Get("l")) {
+		t.Error(tconf, err)
+	}
+	dialect.Interpolate = false
+
+	warnings = nil
+	dialect.Duplicates = DuplicateError
+	_, err = dialect.ReadTyped(strings.NewReader(s))
+	if perr, ok := errors.Cause(err).(*ParseError); !ok || 3 != len(perr.Lines) ||
+		4 != perr.Lines[0].Line || 6 != perr.Lines[1].Line || 0 != len(warnings) {
+		t.Error(err)
+	}
+
+	// a dotted name in one section is not the same property as a name in
+	// another section
+	for _, duplicates := range []DuplicatePolicy{DuplicateError, DuplicateFirstWins, DuplicateList} {
+		dialect.Duplicates = duplicates
+		conf, err = dialect.Read(strings.NewReader("a.b=1\n[a]\nb=2\n"))
+		if nil != err || "1" != conf[""]["a.b"] || "2" != conf["a"]["b"] || 0 != len(warnings) {
+			t.Error(conf, err, warnings)
+		}
+		tconf, err = dialect.ReadTyped(strings.NewReader("a.b=1\n[a]\nb=2\n"))
+		if nil != err || int64(1) != tconf[""]["a.b"] || int64(2) != tconf["a"]["b"] {
+			t.Error(tconf, err)
+		}
+	}
+}
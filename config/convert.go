@@ -0,0 +1,224 @@
+/*
+ * convert.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// formatTyped formats a typed value as text. Strings are returned unchanged.
+func formatTyped(valu interface{}) string {
+	switch v := valu.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		// use "us" rather than "µs" so that the value is written unquoted
+		return strings.Replace(v.String(), "µs", "us", 1)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func toString(valu interface{}) (string, error) {
+	switch v := valu.(type) {
+	case nil:
+		return "", fmt.Errorf("missing value")
+	case []interface{}:
+		return "", fmt.Errorf("cannot convert list to string")
+	default:
+		return formatTyped(v), nil
+	}
+}
+
+func toInt(valu interface{}) (int64, error) {
+	if s, ok := valu.(string); ok {
+		return strconv.ParseInt(strings.TrimSpace(s), 0, 64)
+	}
+	v := reflect.ValueOf(valu)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := v.Uint(); math.MaxInt64 >= u {
+			return int64(u), nil
+		}
+		return 0, fmt.Errorf("value %v out of range", valu)
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); f == math.Trunc(f) &&
+			math.MinInt64 <= f && f < 1<<63 {
+			return int64(f), nil
+		}
+		return 0, fmt.Errorf("value %v is not an integer", valu)
+	}
+	return 0, fmt.Errorf("cannot convert %T to integer", valu)
+}
+
+func toUint(valu interface{}) (uint64, error) {
+	if s, ok := valu.(string); ok {
+		return strconv.ParseUint(strings.TrimSpace(s), 0, 64)
+	}
+	v := reflect.ValueOf(valu)
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := v.Int(); 0 <= i {
+			return uint64(i), nil
+		}
+		return 0, fmt.Errorf("value %v out of range", valu)
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); f == math.Trunc(f) && 0 <= f && f < 1<<64 {
+			return uint64(f), nil
+		}
+		return 0, fmt.Errorf("value %v is not an unsigned integer", valu)
+	}
+	return 0, fmt.Errorf("cannot convert %T to unsigned integer", valu)
+}
+
+func toFloat(valu interface{}) (float64, error) {
+	if s, ok := valu.(string); ok {
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	v := reflect.ValueOf(valu)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to float", valu)
+}
+
+func toBool(valu interface{}) (bool, error) {
+	switch v := valu.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	}
+	return false, fmt.Errorf("cannot convert %T to boolean", valu)
+}
+
+func toDuration(valu interface{}) (time.Duration, error) {
+	switch v := valu.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(strings.TrimSpace(v))
+	}
+	i, err := toInt(valu)
+	if nil != err {
+		return 0, fmt.Errorf("cannot convert %T to duration", valu)
+	}
+	if 0 != i {
+		// a number without unit is almost certainly not meant as nanoseconds
+		return 0, fmt.Errorf("missing unit in duration %d", i)
+	}
+	return 0, nil
+}
+
+// toList converts a value to a list. Strings are split on commas;
+// other values become single element lists.
+func toList(valu interface{}) ([]interface{}, error) {
+	switch v := valu.(type) {
+	case nil:
+		return nil, fmt.Errorf("missing value")
+	case []interface{}:
+		return v, nil
+	case string:
+		if "" == strings.TrimSpace(v) {
+			return []interface{}{}, nil
+		}
+		parts := strings.Split(v, ",")
+		list := make([]interface{}, len(parts))
+		for i, p := range parts {
+			list[i] = strings.TrimSpace(p)
+		}
+		return list, nil
+	}
+	rv := reflect.ValueOf(valu)
+	if reflect.Slice == rv.Kind() || reflect.Array == rv.Kind() {
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = rv.Index(i).Interface()
+		}
+		return list, nil
+	}
+	return []interface{}{valu}, nil
+}
+
+// Typed converts a configuration to a typed configuration. Property values
+// remain strings; the conversion functions of the package (and Unmarshal)
+// convert them to other types as needed.
+func (conf Config) Typed() TypedConfig {
+	tconf := TypedConfig{}
+	for sect, smap := range conf {
+		tsmap := TypedSection{}
+		for k, v := range smap {
+			tsmap[k] = v
+		}
+		tconf[sect] = tsmap
+	}
+	return tconf
+}
+
+// Untyped converts a typed configuration to a configuration. Property values
+// are formatted as text; lists are formatted as comma-separated values.
+func (conf TypedConfig) Untyped() Config {
+	uconf := Config{}
+	for sect, tsmap := range conf {
+		smap := Section{}
+		for k, v := range tsmap {
+			if list, ok := listValue(v); ok {
+				parts := make([]string, len(list))
+				for i, elem := range list {
+					parts[i] = formatTyped(elem)
+				}
+				smap[k] = strings.Join(parts, ", ")
+			} else {
+				smap[k] = formatTyped(v)
+			}
+		}
+		uconf[sect] = smap
+	}
+	return uconf
+}
@@ -0,0 +1,256 @@
+/*
+ * document.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Document is used to edit a configuration file while preserving its layout.
+//
+// A Document retains comments, blank lines, the order of sections and
+// properties and the quoting style of values. Writing a Document produces
+// a file that differs from the original only where properties were changed.
+//
+// When using Get, Set, Delete to manipulate properties the property names
+// follow the syntax SECTION.PROPNAME
+type Document struct {
+	dialect *Dialect
+	items   []item
+	eol     string
+	final   bool
+}
+
+// ReadDocument reads a document from the supplied reader.
+func (dialect *Dialect) ReadDocument(reader io.Reader) (*Document, error) {
+	data, err := ioutil.ReadAll(reader)
+	if nil != err {
+		return nil, err
+	}
+
+	doc := &Document{dialect: dialect, eol: "\n"}
+	if i := bytes.IndexByte(data, '\n'); 0 < i && '\r' == data[i-1] {
+		doc.eol = "\r\n"
+	}
+	doc.final = 0 == len(data) || '\n' == data[len(data)-1]
+
+	p := dialect.newParser(bytes.NewReader(data))
+	for {
+		it, ok := p.next()
+		if !ok {
+			break
+		}
+		doc.items = append(doc.items, it)
+	}
+
+	if err := p.err(); nil != err {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// lookup finds the item that determines the value of a property.
+// Later properties override earlier ones.
+func (doc *Document) lookup(s, k string) int {
+	for i := len(doc.items) - 1; 0 <= i; i-- {
+		it := &doc.items[i]
+		if itemProperty == it.kind &&
+			doc.dialect.sameName(s, it.sect) && doc.dialect.sameName(k, it.name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get gets a property from the document.
+func (doc *Document) Get(k string) string {
+	s, k := splitName(k)
+	i := doc.lookup(s, k)
+	if -1 == i {
+		return ""
+	}
+	return itemString(doc.items[i].valu)
+}
+
+// Set sets a property in the document.
+//
+// An existing property is changed in place. A new property is added after
+// the last property of its section; a new section is added at the end of
+// the document.
+func (doc *Document) Set(k string, v string) {
+	s, k := splitName(k)
+
+	if i := doc.lookup(s, k); -1 != i {
+		it := &doc.items[i]
+		if itemString(it.valu) == v {
+			return
+		}
+		raw := it.raw
+		if -1 == it.voff {
+			raw += string(doc.dialect.AssignChars[0])
+		} else {
+			raw = raw[:it.voff]
+		}
+		quoted := false
+		if q, ok := it.valu.(string); ok && 0 < len(q) && '"' == q[0] {
+			quoted = true
+		}
+		q := doc.dialect.formatValue(v, quoted)
+		it.raw = raw + q
+		it.voff = len(raw)
+		it.valu = itemValue(v, q)
+		return
+	}
+
+	q := doc.dialect.formatValue(v, false)
+	name := quote(k, false)
+	it := item{
+		kind: itemProperty,
+		raw:  name + string(doc.dialect.AssignChars[0]) + q,
+		sect: s,
+		name: k,
+		valu: itemValue(v, q),
+		voff: len(name) + 1,
+	}
+
+	// find insertion point after the last property or header of the section
+	pos := -1
+	for i := range doc.items {
+		c := &doc.items[i]
+		if doc.dialect.sameName(s, c.sect) && (itemProperty == c.kind || itemSection == c.kind) {
+			pos = i + 1
+		}
+	}
+	if -1 != pos {
+		doc.insert(pos, it)
+		return
+	}
+
+	if "" == s {
+		// insert before the first section and its leading comments
+		pos = len(doc.items)
+		for i := range doc.items {
+			if itemSection == doc.items[i].kind {
+				pos = i
+				break
+			}
+		}
+		if len(doc.items) == pos {
+			doc.insert(pos, it)
+			return
+		}
+		for 0 < pos && itemComment == doc.items[pos-1].kind {
+			pos--
+		}
+		doc.insert(pos, it, item{kind: itemBlank})
+		return
+	}
+
+	var items []item
+	if 0 < len(doc.items) && itemBlank != doc.items[len(doc.items)-1].kind {
+		items = append(items, item{kind: itemBlank})
+	}
+	items = append(items,
+		item{kind: itemSection, raw: doc.dialect.formatSection(s), sect: s, voff: -1},
+		it)
+	doc.insert(len(doc.items), items...)
+}
+
+// Delete deletes a property from the document.
+func (doc *Document) Delete(k string) {
+	s, k := splitName(k)
+	items := doc.items[:0]
+	for _, it := range doc.items {
+		if itemProperty == it.kind &&
+			doc.dialect.sameName(s, it.sect) && doc.dialect.sameName(k, it.name) {
+			continue
+		}
+		items = append(items, it)
+	}
+	doc.items = items
+}
+
+// Config returns the configuration contained in the document.
+func (doc *Document) Config() Config {
+	conf := Config{}
+	names := doc.dialect.newNameFolder()
+	for _, it := range doc.items {
+		if itemProperty != it.kind {
+			continue
+		}
+		sect, name := names.fold(it.sect, it.name)
+		smap, ok := conf[sect]
+		if !ok {
+			smap = Section{}
+			conf[sect] = smap
+		}
+		smap[name] = itemString(it.valu)
+	}
+	return conf
+}
+
+// Write writes the document to the supplied writer.
+func (doc *Document) Write(writer io.Writer) error {
+	bufw := bufio.NewWriter(writer)
+
+	for i, it := range doc.items {
+		if 0 < i {
+			bufw.WriteString(doc.eol)
+		}
+		if "\n" != doc.eol {
+			bufw.WriteString(strings.Replace(it.raw, "\n", doc.eol, -1))
+		} else {
+			bufw.WriteString(it.raw)
+		}
+	}
+	if 0 < len(doc.items) && doc.final {
+		bufw.WriteString(doc.eol)
+	}
+
+	return bufw.Flush()
+}
+
+func (doc *Document) insert(pos int, items ...item) {
+	doc.items = append(doc.items, items...)
+	copy(doc.items[pos+len(items):], doc.items[pos:])
+	copy(doc.items[pos:], items)
+}
+
+// itemValue returns the item value of a value v that is written as q.
+// Like values that are read, quoted values are kept in quoted form and
+// other values (including multi-line values) are kept decoded.
+func itemValue(v, q string) string {
+	if 0 < len(q) && '"' == q[0] {
+		return q
+	}
+	return v
+}
+
+func itemString(valu interface{}) string {
+	v, _ := valu.(string)
+	if 0 < len(v) && '"' == v[0] {
+		v, _ = unquote(v)
+	}
+	return v
+}
+
+// ReadDocument reads a document from the supplied reader
+// using the default dialect.
+func ReadDocument(reader io.Reader) (*Document, error) {
+	return DefaultDialect.ReadDocument(reader)
+}
@@ -0,0 +1,168 @@
+/*
+ * document_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDocument = `; header comment
+top = 1
+
+# section comment
+[sect1]
+  name1 : value1
+name2="quoted value"
+
+[sect2]
+empty
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc, err := ReadDocument(strings.NewReader(testDocument))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = doc.Write(&buf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if testDocument != buf.String() {
+		t.Error()
+	}
+
+	conf, err := Read(strings.NewReader(testDocument))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, doc.Config()) {
+		t.Error()
+	}
+
+	doc, err = ReadDocument(strings.NewReader("a=1\r\nb=2"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	doc.Set("b", "3")
+	doc.Write(&buf)
+	if "a=1\r\nb=3" != buf.String() {
+		t.Error()
+	}
+}
+
+func TestDocumentGetSetDelete(t *testing.T) {
+	doc, err := ReadDocument(strings.NewReader(testDocument))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if "1" != doc.Get("top") {
+		t.Error()
+	}
+	if "value1" != doc.Get("sect1.name1") {
+		t.Error()
+	}
+	if "quoted value" != doc.Get("sect1.name2") {
+		t.Error()
+	}
+	if "" != doc.Get("sect2.empty") {
+		t.Error()
+	}
+
+	doc.Set("sect1.name1", "changed")
+	doc.Set("sect1.name2", "changed")
+	doc.Set("sect1.name3", "new value")
+	doc.Set("sect2.empty", "full")
+	doc.Set("sect3.name", "value")
+	doc.Set("new", "2")
+	doc.Delete("top")
+
+	var buf bytes.Buffer
+	err = doc.Write(&buf)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	expected := `; header comment
+new=2
+
+# section comment
+[sect1]
+  name1 : changed
+name2="changed"
+name3="new value"
+
+[sect2]
+empty=full
+
+[sect3]
+name=value
+`
+	if expected != buf.String() {
+		t.Errorf("unexpected document:\n%s", buf.String())
+	}
+}
+
+func TestDocumentContinuation(t *testing.T) {
+	for _, dialect := range []*Dialect{
+		{AssignChars: "=", CommentChars: "#", BackslashContinuation: true},
+		{AssignChars: "=", CommentChars: "#", IndentContinuation: true},
+	} {
+		doc, err := dialect.ReadDocument(strings.NewReader("[s]\nk=v\n"))
+		if nil != err {
+			t.Fatal(err)
+		}
+
+		doc.Set("s.k", "a\nb")
+		doc.Set("s.n", "c\nd")
+		doc.Set("s.q", "\"e\nf")
+		if "a\nb" != doc.Get("s.k") || "c\nd" != doc.Get("s.n") || "\"e\nf" != doc.Get("s.q") {
+			t.Error(doc.Get("s.k"), doc.Get("s.n"), doc.Get("s.q"))
+		}
+		expected := Config{"s": Section{"k": "a\nb", "n": "c\nd", "q": "\"e\nf"}}
+		if !reflect.DeepEqual(expected, doc.Config()) {
+			t.Error(doc.Config())
+		}
+
+		// setting the same value again leaves the document unchanged
+		var buf bytes.Buffer
+		err = doc.Write(&buf)
+		if nil != err {
+			t.Fatal(err)
+		}
+		text := buf.String()
+		doc.Set("s.k", "a\nb")
+		buf.Reset()
+		err = doc.Write(&buf)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if text != buf.String() {
+			t.Error(buf.String())
+		}
+
+		doc, err = dialect.ReadDocument(strings.NewReader(text))
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, doc.Config()) {
+			t.Error(text)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+/*
+ * dotenv.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// ReadEnv reads a typed configuration from a .env file.
+//
+// Every line of a .env file contains an assignment of the form NAME=VALUE,
+// optionally preceded by the keyword export. Lines that are blank or start
+// with # are ignored. Values may be double quoted (with backslash escapes),
+// single quoted (without escapes) or unquoted; quoted values may span
+// multiple lines. Unquoted values end at a # that is preceded by white space.
+//
+// Only variables whose names start with prefix are considered. The prefix
+// is removed and the remaining name is mapped to a property name using the
+// mapping function as in OverlayEnv. Quoted values are read as strings;
+// unquoted values are typed using the same rules as ReadTyped.
+func (dialect *Dialect) ReadEnv(reader io.Reader,
+	prefix string, mapping func(name string) string) (TypedConfig, error) {
+	if nil == mapping {
+		mapping = EnvName
+	}
+
+	scan := bufio.NewScanner(reader)
+	lineno := 0
+	fail := func(msg string) error {
+		return errors.New(fmt.Sprintf("line %d: %s", lineno, msg), nil, ErrConfig)
+	}
+
+	conf := TypedConfig{}
+	for scan.Scan() {
+		lineno++
+		line := strings.TrimSpace(scan.Text())
+		if "" == line || '#' == line[0] {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimSpace(line[len("export "):])
+		}
+
+		i := strings.IndexByte(line, '=')
+		if -1 == i {
+			return nil, fail("missing =")
+		}
+		key := strings.TrimSpace(line[:i])
+		if !validEnvName(key) {
+			return nil, fail(fmt.Sprintf("invalid name %q", key))
+		}
+		line = strings.TrimLeft(line[i+1:], " \t")
+
+		var valu interface{}
+		if 0 < len(line) && ('"' == line[0] || '\'' == line[0]) {
+			q := line[0]
+			text := line[1:]
+			for {
+				j := envQuoteEnd(text, q)
+				if -1 != j {
+					rest := strings.TrimSpace(text[j+1:])
+					if "" != rest && '#' != rest[0] {
+						return nil, fail("unexpected text after closing quote")
+					}
+					text = text[:j]
+					break
+				}
+				if !scan.Scan() {
+					return nil, fail("unterminated quoted value")
+				}
+				lineno++
+				text += "\n" + scan.Text()
+			}
+			if '"' == q {
+				text = envUnescape(text)
+			}
+			valu = text
+		} else {
+			for j := 1; len(line) > j; j++ {
+				if '#' == line[j] && (' ' == line[j-1] || '\t' == line[j-1]) {
+					line = line[:j]
+					break
+				}
+			}
+			valu = dialect.inferValue(strings.TrimSpace(line))
+		}
+
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		name := mapping(key[len(prefix):])
+		if "" == name {
+			continue
+		}
+		conf.Set(name, valu)
+	}
+
+	err := scan.Err()
+	if nil != err {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func validEnvName(s string) bool {
+	if "" == s || ('0' <= s[0] && s[0] <= '9') {
+		return false
+	}
+	for i := 0; len(s) > i; i++ {
+		if c := s[i]; !('A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' ||
+			'0' <= c && c <= '9' || '_' == c) {
+			return false
+		}
+	}
+	return true
+}
+
+// envQuoteEnd returns the index of the closing quote q in s or -1.
+func envQuoteEnd(s string, q byte) int {
+	for i := 0; len(s) > i; i++ {
+		switch s[i] {
+		case '\\':
+			if '"' == q {
+				i++
+			}
+		case q:
+			return i
+		}
+	}
+	return -1
+}
+
+func envUnescape(s string) string {
+	buf := bytes.Buffer{}
+	for i := 0; len(s) > i; i++ {
+		c := s[i]
+		if '\\' == c && len(s) > i+1 {
+			i++
+			switch c = s[i]; c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case '"', '\\', '$':
+			default:
+				buf.WriteByte('\\')
+			}
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}
+
+// EnvVarName maps a property name (SECTION.PROPNAME) to the name of an
+// environment variable (without prefix). It is the inverse of EnvName:
+// the name is converted to upper case, dots are replaced by double
+// underscores and other characters that are not valid in environment
+// variable names are replaced by single underscores.
+func EnvVarName(name string) string {
+	buf := bytes.Buffer{}
+	for i := 0; len(name) > i; i++ {
+		switch c := name[i]; {
+		case 'a' <= c && c <= 'z':
+			buf.WriteByte(c - 'a' + 'A')
+		case 'A' <= c && c <= 'Z', '0' <= c && c <= '9', '_' == c:
+			buf.WriteByte(c)
+		case '.' == c:
+			buf.WriteString("__")
+		default:
+			buf.WriteByte('_')
+		}
+	}
+	return buf.String()
+}
+
+// WriteEnv writes a typed configuration as a .env file. Variable names are
+// formed by prepending prefix to the names returned by EnvVarName. Strings
+// are written double quoted so that they are read back as strings; lists
+// cannot be represented and are reported as errors.
+func WriteEnv(writer io.Writer, conf TypedConfig, prefix string) error {
+	type envProperty struct {
+		sect, name, envName string
+	}
+	var props []envProperty
+	for sect, tsmap := range conf {
+		for name, valu := range tsmap {
+			if _, ok := listValue(valu); ok {
+				return errors.New(
+					fmt.Sprintf("cannot write list %s", propertyName(sect, name)),
+					nil, ErrConfig)
+			}
+			props = append(props,
+				envProperty{sect, name, EnvVarName(propertyName(sect, name))})
+		}
+	}
+	sort.Slice(props, func(i, j int) bool {
+		if props[i].envName != props[j].envName {
+			return props[i].envName < props[j].envName
+		}
+		if props[i].sect != props[j].sect {
+			return props[i].sect < props[j].sect
+		}
+		return props[i].name < props[j].name
+	})
+
+	bufw := bufio.NewWriter(writer)
+	for _, prop := range props {
+		bufw.WriteString(prefix)
+		bufw.WriteString(prop.envName)
+		bufw.WriteByte('=')
+		switch v := conf[prop.sect][prop.name].(type) {
+		case string:
+			bufw.WriteString(envQuote(v))
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+			float32, float64:
+			bufw.WriteString(formatTyped(v))
+		default:
+			bufw.WriteString(envQuote(formatTyped(v)))
+		}
+		bufw.WriteByte('\n')
+	}
+
+	return bufw.Flush()
+}
+
+func envQuote(s string) string {
+	buf := bytes.Buffer{}
+	buf.WriteByte('"')
+	for i := 0; len(s) > i; i++ {
+		switch c := s[i]; c {
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '"', '\\', '$':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// ReadEnv reads a typed configuration from a .env file
+// using the default dialect.
+func ReadEnv(reader io.Reader,
+	prefix string, mapping func(name string) string) (TypedConfig, error) {
+	return DefaultDialect.ReadEnv(reader, prefix, mapping)
+}
@@ -0,0 +1,117 @@
+/*
+ * dotenv_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadEnv(t *testing.T) {
+	conf, err := ReadEnv(strings.NewReader(`# comment
+APP_NAME=app
+export APP_SERVER__PORT=8080 # port
+APP_SERVER__HOST = "local host"
+APP_RATIO=0.5
+APP_QUOTED="42"
+APP_RAW='a\nb $x'
+APP_MULTI="line1
+line2\t$"
+APP_EMPTY=
+OTHER=ignored
+`), "APP_", nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	expected := TypedConfig{
+		"": TypedSection{
+			"name":   "app",
+			"ratio":  0.5,
+			"quoted": "42",
+			"raw":    `a\nb $x`,
+			"multi":  "line1\nline2\t$",
+			"empty":  "",
+		},
+		"server": TypedSection{
+			"port": int64(8080),
+			"host": "local host",
+		},
+	}
+	if !reflect.DeepEqual(expected, conf) {
+		t.Error(conf)
+	}
+
+	for _, s := range []string{
+		"A\n",
+		"1A=b\n",
+		"A=\"b\n",
+		"A=\"b\" c\n",
+	} {
+		if _, err := ReadEnv(strings.NewReader(s), "", nil); nil == err {
+			t.Error(s)
+		}
+	}
+}
+
+func TestWriteEnv(t *testing.T) {
+	conf := TypedConfig{
+		"": TypedSection{
+			"name":    "say \"hi\"\n$HOME",
+			"count":   "42",
+			"debug":   true,
+			"timeout": 5 * time.Second,
+			"a.b":     "dotted",
+		},
+		"server": TypedSection{
+			"port":      8080,
+			"log-level": "info",
+			"x.y":       1,
+		},
+	}
+
+	buf := bytes.Buffer{}
+	err := WriteEnv(&buf, conf, "APP_")
+	if nil != err {
+		t.Fatal(err)
+	}
+	expected := `APP_A__B="dotted"
+APP_COUNT="42"
+APP_DEBUG=true
+APP_NAME="say \"hi\"\n\$HOME"
+APP_SERVER__LOG_LEVEL="info"
+APP_SERVER__PORT=8080
+APP_SERVER__X__Y=1
+APP_TIMEOUT="5s"
+`
+	if expected != buf.String() {
+		t.Error(buf.String())
+	}
+
+	conf2, err := ReadEnv(&buf, "APP_", nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "42" != conf2.Get("count") || conf.Get("name") != conf2.Get("name") ||
+		int64(8080) != conf2.Get("server.port") || "5s" != conf2.Get("timeout") {
+		t.Error(conf2)
+	}
+
+	err = WriteEnv(&buf, TypedConfig{"": TypedSection{"a": []interface{}{1}}}, "")
+	if nil == err {
+		t.Error()
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * encrypt.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"encoding/base64"
+
+	"github.com/billziss-gh/golib/errors"
+	"github.com/billziss-gh/golib/util"
+)
+
+// encPrefix is the prefix of encrypted values.
+const encPrefix = "enc:"
+
+// cipherKey gets the key used to decrypt and encrypt values.
+func (dialect *Dialect) cipherKey() ([]byte, error) {
+	if nil == dialect.Key {
+		return nil, errors.New("missing encryption key", nil, ErrConfig)
+	}
+	key, err := dialect.Key()
+	if nil != err {
+		return nil, errors.New("cannot get encryption key", err, ErrConfig)
+	}
+	return key, nil
+}
+
+// encrypted determines whether a property is encrypted during writing.
+func (dialect *Dialect) encrypted(sect, name string) bool {
+	for _, pattern := range dialect.Encrypted {
+		s, n := splitName(pattern)
+		if s == sect && ("*" == n || n == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// decryptValue decrypts a value of the form enc:DATA. The result is the
+// value as it would appear in a file (i.e. possibly quoted).
+func (dialect *Dialect) decryptValue(state *readState, v string) (string, error) {
+	if nil == state.key {
+		key, err := dialect.cipherKey()
+		if nil != err {
+			return "", err
+		}
+		state.key = key
+	}
+
+	data, err := base64.StdEncoding.DecodeString(v[len(encPrefix):])
+	if nil != err {
+		return "", err
+	}
+
+	data, err = util.OpenAeData(data, state.key)
+	if nil != err {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// encrypter encrypts values during writing. The key is fetched when the
+// first value is encrypted.
+type encrypter struct {
+	dialect *Dialect
+	key     []byte
+}
+
+func (dialect *Dialect) newEncrypter() *encrypter {
+	return &encrypter{dialect: dialect}
+}
+
+// encrypt encrypts a value as it would appear in a file. A nil encrypter
+// returns the value unchanged.
+func (enc *encrypter) encrypt(v string) (string, error) {
+	if nil == enc {
+		return v, nil
+	}
+
+	if nil == enc.key {
+		key, err := enc.dialect.cipherKey()
+		if nil != err {
+			return "", err
+		}
+		enc.key = key
+	}
+
+	data, err := util.SealAeData([]byte(v), enc.key)
+	if nil != err {
+		return "", err
+	}
+
+	return encPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
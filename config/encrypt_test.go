@@ -0,0 +1,130 @@
+/*
+ * encrypt_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+func encryptDialect() *Dialect {
+	dialect := *DefaultDialect
+	dialect.Lists = true
+	dialect.Key = func() ([]byte, error) {
+		return []byte("passpasspasspass"), nil
+	}
+	dialect.Encrypted = []string{"auth.token", "secrets.*"}
+	return &dialect
+}
+
+func TestEncryptWrite(t *testing.T) {
+	dialect := encryptDialect()
+
+	conf := Config{
+		"auth":    Section{"token": "s3cr3t value", "user": "bill"},
+		"secrets": Section{"a": "1", "b": "line1\nline2"},
+	}
+
+	buf := bytes.Buffer{}
+	err := dialect.Write(&buf, conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if strings.Contains(s, "s3cr3t") || strings.Contains(s, "line1") ||
+		!strings.Contains(s, "user=bill") || 3 != strings.Count(s, "="+encPrefix) {
+		t.Error(s)
+	}
+
+	conf2, err := dialect.Read(strings.NewReader(s))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, conf2) {
+		t.Error(conf2)
+	}
+
+	// without a key encrypted values are read as they are
+	dialect2 := *dialect
+	dialect2.Key = nil
+	conf2, err = dialect2.Read(strings.NewReader(s))
+	if nil != err || !strings.HasPrefix(conf2.Get("auth.token"), encPrefix) {
+		t.Error(err)
+	}
+	err = dialect2.Write(&buf, conf)
+	if nil == err || ErrConfig != errors.Attachment(err) {
+		t.Error(err)
+	}
+
+	dialect2.Key = func() ([]byte, error) {
+		return []byte("wrongwrongwrongw"), nil
+	}
+	_, err = dialect2.Read(strings.NewReader(s))
+	if nil == err {
+		t.Error()
+	}
+}
+
+func TestEncryptWriteTyped(t *testing.T) {
+	dialect := encryptDialect()
+
+	conf := TypedConfig{
+		"auth":    TypedSection{"token": "42", "user": "bill"},
+		"secrets": TypedSection{"n": int64(42), "on": true, "l": []interface{}{"x", int64(1)}},
+	}
+
+	buf := bytes.Buffer{}
+	err := dialect.WriteTyped(&buf, conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if 5 != strings.Count(s, "="+encPrefix) {
+		t.Error(s)
+	}
+
+	conf2, err := dialect.ReadTyped(strings.NewReader(s))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, conf2) {
+		t.Error(conf2)
+	}
+
+	conf3, err := dialect.ReadTyped(strings.NewReader("a=\"enc:abc\"\n"))
+	if nil != err || "enc:abc" != conf3.Get("a") {
+		t.Error(conf3, err)
+	}
+}
+
+func TestEncryptNoKey(t *testing.T) {
+	conf, err := Read(strings.NewReader("codec=enc:utf8\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "enc:utf8" != conf.Get("codec") {
+		t.Error(conf)
+	}
+
+	tconf, err := ReadTyped(strings.NewReader("codec=enc:utf8\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "enc:utf8" != tconf.Get("codec") {
+		t.Error(tconf)
+	}
+}
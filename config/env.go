@@ -0,0 +1,68 @@
+/*
+ * env.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// EnvName maps the name of an environment variable (with its prefix
+// removed) to a property name. The name is converted to lower case and
+// double underscores are interpreted as section separators. For example
+// SECTION__NAME maps to section.name and NAME maps to name.
+func EnvName(name string) string {
+	parts := strings.Split(strings.ToLower(name), "__")
+	for _, p := range parts {
+		if "" == p {
+			return ""
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// OverlayEnv overlays a typed configuration with environment variables.
+//
+// Only environment variables whose names start with prefix are considered.
+// The prefix is removed and the remaining name is mapped to a property name
+// (SECTION.PROPNAME) using the mapping function; if mapping is nil EnvName
+// is used. Variables that map to the empty string are ignored. Values are
+// typed using the same rules as ReadTyped.
+//
+// OverlayEnv is typically called after reading a configuration file and
+// before applying command line flags (see package config/flag), so that
+// environment variables override the file and flags override both.
+func (dialect *Dialect) OverlayEnv(conf TypedConfig,
+	prefix string, mapping func(name string) string) {
+	if nil == mapping {
+		mapping = EnvName
+	}
+
+	for _, e := range os.Environ() {
+		i := strings.IndexByte(e, '=')
+		if -1 == i || !strings.HasPrefix(e[:i], prefix) {
+			continue
+		}
+		name := mapping(e[len(prefix):i])
+		if "" == name {
+			continue
+		}
+		conf.Set(name, dialect.typedValue(e[i+1:]))
+	}
+}
+
+// OverlayEnv overlays a typed configuration with environment variables
+// using the default dialect.
+func OverlayEnv(conf TypedConfig, prefix string, mapping func(name string) string) {
+	DefaultDialect.OverlayEnv(conf, prefix, mapping)
+}
@@ -0,0 +1,74 @@
+/*
+ * env_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	if "section.name" != EnvName("SECTION__NAME") {
+		t.Error()
+	}
+	if "log_level" != EnvName("LOG_LEVEL") {
+		t.Error()
+	}
+	if "a.b.c_d" != EnvName("A__B__C_D") {
+		t.Error()
+	}
+	if "" != EnvName("A____B") || "" != EnvName("") {
+		t.Error()
+	}
+}
+
+func TestOverlayEnv(t *testing.T) {
+	env := map[string]string{
+		"CONFIGTEST_SERVER__PORT":  "9090",
+		"CONFIGTEST_SERVER__DEBUG": "true",
+		"CONFIGTEST_NAME":          `"42"`,
+		"CONFIGTEST_RATIO":         "0.5",
+		"CONFIGTEST_EMPTY":         "",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	conf, err := ReadTyped(strings.NewReader("name=app\n[server]\nport=8080\nhost=localhost\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	OverlayEnv(conf, "CONFIGTEST_", nil)
+	if int64(9090) != conf.Get("server.port") ||
+		true != conf.Get("server.debug") ||
+		"localhost" != conf.Get("server.host") ||
+		"42" != conf.Get("name") ||
+		0.5 != conf.Get("ratio") ||
+		"" != conf.Get("empty") {
+		t.Error(conf)
+	}
+
+	conf = TypedConfig{}
+	OverlayEnv(conf, "CONFIGTEST_", func(name string) string {
+		if "NAME" == name {
+			return "app.name"
+		}
+		return ""
+	})
+	if 1 != len(conf) || "42" != conf.Get("app.name") {
+		t.Error(conf)
+	}
+}
@@ -16,11 +16,26 @@ package flag
 
 import (
 	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/billziss-gh/golib/config"
+	"github.com/billziss-gh/golib/errors"
 )
 
+// value gets the value of a flag. Flags whose Value does not implement
+// flag.Getter are reported as strings.
+func value(f *flag.Flag) interface{} {
+	if g, ok := f.Value.(flag.Getter); ok {
+		return g.Get()
+	}
+	return f.Value.String()
+}
+
 // Visit gets the flags present in a command line as a typed configuration section.
+// Flags whose Value does not implement flag.Getter are reported as strings.
 func Visit(flagSet *flag.FlagSet, section config.TypedSection, names ...string) {
 	if nil == flagSet {
 		flagSet = flag.CommandLine
@@ -28,18 +43,14 @@ func Visit(flagSet *flag.FlagSet, section config.TypedSection, names ...string)
 
 	if 0 == len(names) {
 		flagSet.Visit(func(f *flag.Flag) {
-			if g, ok := f.Value.(flag.Getter); ok {
-				section[f.Name] = g.Get()
-			}
+			section[f.Name] = value(f)
 		})
 	} else {
 		// Use Visit instead of Lookup as we only want flags that were actually set.
 		flagSet.Visit(func(f *flag.Flag) {
 			for _, n := range names {
 				if f.Name == n {
-					if g, ok := f.Value.(flag.Getter); ok {
-						section[f.Name] = g.Get()
-					}
+					section[f.Name] = value(f)
 					break
 				}
 			}
@@ -48,6 +59,7 @@ func Visit(flagSet *flag.FlagSet, section config.TypedSection, names ...string)
 }
 
 // VisitAll gets all flags as a typed configuration section.
+// Flags whose Value does not implement flag.Getter are reported as strings.
 func VisitAll(flagSet *flag.FlagSet, section config.TypedSection, names ...string) {
 	if nil == flagSet {
 		flagSet = flag.CommandLine
@@ -55,17 +67,222 @@ func VisitAll(flagSet *flag.FlagSet, section config.TypedSection, names ...strin
 
 	if 0 == len(names) {
 		flagSet.VisitAll(func(f *flag.Flag) {
-			if g, ok := f.Value.(flag.Getter); ok {
-				section[f.Name] = g.Get()
-			}
+			section[f.Name] = value(f)
 		})
 	} else {
 		for _, n := range names {
 			if f := flagSet.Lookup(n); nil != f {
-				if g, ok := f.Value.(flag.Getter); ok {
-					section[f.Name] = g.Get()
+				section[f.Name] = value(f)
+			}
+		}
+	}
+}
+
+// format formats a typed value as a flag value.
+func format(valu interface{}) string {
+	switch v := valu.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// SetDefaults sets the values of flags from a typed configuration section.
+// Properties are matched to flags by name; properties without a matching
+// flag are ignored. Flags that were set on the command line are not
+// changed, so that SetDefaults may be called before or after parsing.
+// The default value reported in the usage message is also updated.
+//
+// List values set list flags (see Register) or call Set once per element
+// for other flags.
+func SetDefaults(flagSet *flag.FlagSet, section config.TypedSection, names ...string) error {
+	if nil == flagSet {
+		flagSet = flag.CommandLine
+	}
+
+	set := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if 0 == len(names) {
+		for n := range section {
+			names = append(names, n)
+		}
+	}
+
+	for _, n := range names {
+		valu, ok := section[n]
+		if !ok || set[n] {
+			continue
+		}
+		f := flagSet.Lookup(n)
+		if nil == f {
+			continue
+		}
+
+		var err error
+		if list, ok := valu.([]interface{}); ok {
+			if l, ok := f.Value.(*listValue); ok {
+				*l = listValue{list: list}
+			} else {
+				for _, elem := range list {
+					err = f.Value.Set(format(elem))
+					if nil != err {
+						break
+					}
 				}
 			}
+		} else {
+			err = f.Value.Set(format(valu))
+		}
+		if nil != err {
+			return errors.New(fmt.Sprintf("cannot set flag %s", n), err, config.ErrConfig)
 		}
+		f.DefValue = f.Value.String()
 	}
+
+	return nil
+}
+
+// listValue is a flag.Value for lists. The first Set replaces the default
+// value; subsequent calls append to the list.
+type listValue struct {
+	list []interface{}
+	set  bool
+}
+
+func (l *listValue) String() string {
+	if nil == l {
+		return ""
+	}
+	parts := make([]string, len(l.list))
+	for i, elem := range l.list {
+		parts[i] = format(elem)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	if !l.set {
+		l.list = nil
+		l.set = true
+	}
+	l.list = append(l.list, s)
+	return nil
+}
+
+func (l *listValue) Get() interface{} {
+	list := make([]interface{}, len(l.list))
+	copy(list, l.list)
+	return list
+}
+
+// define defines a flag whose type is determined by a typed value.
+func define(flagSet *flag.FlagSet, name string, valu interface{}, usage string) error {
+	switch v := valu.(type) {
+	case bool:
+		flagSet.Bool(name, v, usage)
+	case string:
+		flagSet.String(name, v, usage)
+	case int:
+		flagSet.Int(name, v, usage)
+	case int64:
+		flagSet.Int64(name, v, usage)
+	case uint:
+		flagSet.Uint(name, v, usage)
+	case uint64:
+		flagSet.Uint64(name, v, usage)
+	case float64:
+		flagSet.Float64(name, v, usage)
+	case time.Duration:
+		flagSet.Duration(name, v, usage)
+	case []interface{}:
+		flagSet.Var(&listValue{list: v}, name, usage)
+	default:
+		return errors.New(
+			fmt.Sprintf("cannot define flag %s of type %T", name, valu), nil, config.ErrConfig)
+	}
+	return nil
+}
+
+// Register defines flags for the properties of a typed configuration
+// section. The type of each flag is determined by the type of the property
+// value, which also becomes the flag default. Lists are registered as
+// repeatable flags: the first occurrence on the command line replaces the
+// default and later occurrences append to it. Properties that already have
+// a flag are skipped.
+func Register(flagSet *flag.FlagSet, section config.TypedSection, names ...string) error {
+	if nil == flagSet {
+		flagSet = flag.CommandLine
+	}
+
+	if 0 == len(names) {
+		for n := range section {
+			names = append(names, n)
+		}
+	}
+
+	for _, n := range names {
+		valu, ok := section[n]
+		if !ok || nil != flagSet.Lookup(n) {
+			continue
+		}
+		err := define(flagSet, n, valu, "")
+		if nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterSchema defines flags for the properties of a schema that belong
+// to the named section. Flags are named after the property names without
+// the section; their usage messages are the property help texts. Properties
+// without defaults get the zero value of their type as the flag default.
+// Properties that already have a flag are skipped.
+func RegisterSchema(flagSet *flag.FlagSet, schema *config.Schema, sect string) error {
+	if nil == flagSet {
+		flagSet = flag.CommandLine
+	}
+
+	for _, prop := range schema.Properties {
+		s, n := "", prop.Name
+		if i := strings.LastIndex(n, "."); -1 != i {
+			s, n = n[:i], n[i+1:]
+		}
+		if s != sect || nil != flagSet.Lookup(n) {
+			continue
+		}
+
+		valu := prop.Default
+		if nil == valu {
+			valu = zero[prop.Type]
+		}
+		v, err := prop.Type.Convert(valu)
+		if nil != err {
+			return errors.New(fmt.Sprintf("cannot define flag %s", n), err, config.ErrConfig)
+		}
+		err = define(flagSet, n, v, prop.Help)
+		if nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var zero = map[config.Type]interface{}{
+	config.TypeString:   "",
+	config.TypeInt:      int64(0),
+	config.TypeFloat:    float64(0),
+	config.TypeBool:     false,
+	config.TypeDuration: time.Duration(0),
+	config.TypeList:     []interface{}{},
 }
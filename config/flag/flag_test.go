@@ -0,0 +1,175 @@
+/*
+ * flag_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package flag
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/billziss-gh/golib/config"
+)
+
+type stringValue string
+
+func (s *stringValue) String() string {
+	return string(*s)
+}
+
+func (s *stringValue) Set(v string) error {
+	*s = stringValue(v)
+	return nil
+}
+
+func newFlagSet() *flag.FlagSet {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.SetOutput(ioutil.Discard)
+	return flagSet
+}
+
+func TestVisit(t *testing.T) {
+	flagSet := newFlagSet()
+	flagSet.Int("port", 80, "")
+	flagSet.String("host", "localhost", "")
+	name := stringValue("default")
+	flagSet.Var(&name, "name", "")
+
+	err := flagSet.Parse([]string{"-port", "8080", "-name", "app"})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	section := config.TypedSection{}
+	Visit(flagSet, section)
+	if !reflect.DeepEqual(config.TypedSection{"port": 8080, "name": "app"}, section) {
+		t.Error(section)
+	}
+
+	section = config.TypedSection{}
+	VisitAll(flagSet, section, "host", "name")
+	if !reflect.DeepEqual(config.TypedSection{"host": "localhost", "name": "app"}, section) {
+		t.Error(section)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	flagSet := newFlagSet()
+	port := flagSet.Int("port", 80, "")
+	host := flagSet.String("host", "localhost", "")
+	timeout := flagSet.Duration("timeout", time.Second, "")
+
+	err := flagSet.Parse([]string{"-port", "8080"})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	err = SetDefaults(flagSet, config.TypedSection{
+		"port":    int64(9090),
+		"host":    "example.com",
+		"timeout": 5 * time.Second,
+		"unknown": true,
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 8080 != *port || "example.com" != *host || 5*time.Second != *timeout {
+		t.Error(*port, *host, *timeout)
+	}
+	if "example.com" != flagSet.Lookup("host").DefValue {
+		t.Error()
+	}
+
+	err = SetDefaults(flagSet, config.TypedSection{"timeout": "bogus"})
+	if nil == err {
+		t.Error()
+	}
+}
+
+func TestRegister(t *testing.T) {
+	section := config.TypedSection{
+		"port":    int64(80),
+		"ratio":   0.5,
+		"debug":   false,
+		"name":    "app",
+		"timeout": time.Second,
+		"hosts":   []interface{}{"a", "b"},
+	}
+
+	flagSet := newFlagSet()
+	err := Register(flagSet, section)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	err = flagSet.Parse([]string{"-port", "8080", "-debug", "-hosts", "x", "-hosts", "y"})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	res := config.TypedSection{}
+	VisitAll(flagSet, res)
+	expected := config.TypedSection{
+		"port":    int64(8080),
+		"ratio":   0.5,
+		"debug":   true,
+		"name":    "app",
+		"timeout": time.Second,
+		"hosts":   []interface{}{"x", "y"},
+	}
+	if !reflect.DeepEqual(expected, res) {
+		t.Error(res)
+	}
+
+	err = Register(newFlagSet(), config.TypedSection{"bad": struct{}{}})
+	if nil == err {
+		t.Error()
+	}
+}
+
+func TestRegisterSchema(t *testing.T) {
+	schema := &config.Schema{
+		Properties: []config.Property{
+			{Name: "server.port", Type: config.TypeInt, Default: 80, Help: "listen port"},
+			{Name: "server.timeout", Type: config.TypeDuration, Default: "5s"},
+			{Name: "server.verbose", Type: config.TypeBool},
+			{Name: "client.port", Type: config.TypeInt},
+		},
+	}
+
+	flagSet := newFlagSet()
+	err := RegisterSchema(flagSet, schema, "server")
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	f := flagSet.Lookup("port")
+	if nil == f || "80" != f.DefValue || "listen port" != f.Usage {
+		t.Error(f)
+	}
+	f = flagSet.Lookup("timeout")
+	if nil == f || "5s" != f.DefValue {
+		t.Error(f)
+	}
+	f = flagSet.Lookup("verbose")
+	if nil == f || "false" != f.DefValue {
+		t.Error(f)
+	}
+
+	n := 0
+	flagSet.VisitAll(func(*flag.Flag) { n++ })
+	if 3 != n {
+		t.Error(n)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ * fold.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// foldCase folds the case of a name. All case-insensitive matching of
+// section and property names uses this rule, so that names that match
+// when looked up also match when detecting duplicates or merging.
+func foldCase(name string) string {
+	return strings.ToLower(name)
+}
+
+// foldName returns the name used to match a section or property name.
+func (dialect *Dialect) foldName(name string) string {
+	if dialect.IgnoreCase {
+		return foldCase(name)
+	}
+	return name
+}
+
+// sameName determines whether two section or property names match.
+func (dialect *Dialect) sameName(a, b string) bool {
+	return dialect.foldName(a) == dialect.foldName(b)
+}
+
+// nameFolder maps names read from a file to the casing of their first
+// occurrence when the dialect ignores case.
+type nameFolder struct {
+	dialect *Dialect
+	sects   map[string]string
+	props   map[string]string
+}
+
+func (dialect *Dialect) newNameFolder() *nameFolder {
+	return &nameFolder{
+		dialect: dialect,
+		sects:   map[string]string{},
+		props:   map[string]string{},
+	}
+}
+
+func (names *nameFolder) fold(sect, name string) (string, string) {
+	if !names.dialect.IgnoreCase {
+		return sect, name
+	}
+
+	k := foldCase(sect)
+	if s, ok := names.sects[k]; ok {
+		sect = s
+	} else {
+		names.sects[k] = sect
+	}
+
+	k += "\x00" + foldCase(name)
+	if n, ok := names.props[k]; ok {
+		name = n
+	} else {
+		names.props[k] = name
+	}
+
+	return sect, name
+}
+
+// findName finds a section or property name using case-insensitive matching.
+// The first matching name in sorted order is returned. Callers look up exact
+// names directly before calling findName, which sorts the names.
+func findName(names []string, s string) (string, bool) {
+	f := foldCase(s)
+	sort.Strings(names)
+	for _, n := range names {
+		if foldCase(n) == f {
+			return n, true
+		}
+	}
+	return s, false
+}
+
+// FoldConfig is used to access a configuration using case-insensitive
+// matching of section and property names. Converting a Config to a
+// FoldConfig does not copy it; changes are made to the original Config,
+// which preserves the casing of existing names.
+//
+// When using Get, Set, Delete to manipulate properties the property names
+// follow the syntax SECTION.PROPNAME
+type FoldConfig Config
+
+func (conf FoldConfig) find(k string) (string, string, bool) {
+	s, n := splitName(k)
+	if _, ok := conf[s]; !ok {
+		sects := make([]string, 0, len(conf))
+		for sect := range conf {
+			sects = append(sects, sect)
+		}
+		if s, ok = findName(sects, s); !ok {
+			return s, n, false
+		}
+	}
+	if _, ok := conf[s][n]; ok {
+		return s, n, true
+	}
+	props := make([]string, 0, len(conf[s]))
+	for name := range conf[s] {
+		props = append(props, name)
+	}
+	n, ok := findName(props, n)
+	return s, n, ok
+}
+
+// Lookup gets a property from the configuration and reports whether
+// it exists.
+func (conf FoldConfig) Lookup(k string) (string, bool) {
+	s, n, ok := conf.find(k)
+	if !ok {
+		return "", false
+	}
+	return conf[s][n], true
+}
+
+// Get gets a property from the configuration.
+func (conf FoldConfig) Get(k string) string {
+	v, _ := conf.Lookup(k)
+	return v
+}
+
+// Set sets a property in the configuration. The casing of an existing
+// section or property is preserved.
+func (conf FoldConfig) Set(k string, v string) {
+	s, n, _ := conf.find(k)
+	Config(conf).Set(propertyName(s, n), v)
+}
+
+// Delete deletes a property from the configuration.
+func (conf FoldConfig) Delete(k string) {
+	s, n, ok := conf.find(k)
+	if ok {
+		Config(conf).Delete(propertyName(s, n))
+	}
+}
+
+// FoldTypedConfig is used to access a typed configuration using
+// case-insensitive matching of section and property names.
+// See FoldConfig.
+type FoldTypedConfig TypedConfig
+
+func (conf FoldTypedConfig) find(k string) (string, string, bool) {
+	s, n := splitName(k)
+	if _, ok := conf[s]; !ok {
+		sects := make([]string, 0, len(conf))
+		for sect := range conf {
+			sects = append(sects, sect)
+		}
+		if s, ok = findName(sects, s); !ok {
+			return s, n, false
+		}
+	}
+	if _, ok := conf[s][n]; ok {
+		return s, n, true
+	}
+	props := make([]string, 0, len(conf[s]))
+	for name := range conf[s] {
+		props = append(props, name)
+	}
+	n, ok := findName(props, n)
+	return s, n, ok
+}
+
+// Lookup gets a property from the configuration and reports whether
+// it exists.
+func (conf FoldTypedConfig) Lookup(k string) (interface{}, bool) {
+	s, n, ok := conf.find(k)
+	if !ok {
+		return nil, false
+	}
+	return conf[s][n], true
+}
+
+// Get gets a property from the configuration.
+func (conf FoldTypedConfig) Get(k string) interface{} {
+	v, _ := conf.Lookup(k)
+	return v
+}
+
+// Set sets a property in the configuration. The casing of an existing
+// section or property is preserved.
+func (conf FoldTypedConfig) Set(k string, v interface{}) {
+	s, n, _ := conf.find(k)
+	TypedConfig(conf).Set(propertyName(s, n), v)
+}
+
+// Delete deletes a property from the configuration.
+func (conf FoldTypedConfig) Delete(k string) {
+	s, n, ok := conf.find(k)
+	if ok {
+		TypedConfig(conf).Delete(propertyName(s, n))
+	}
+}
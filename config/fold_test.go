@@ -0,0 +1,120 @@
+/*
+ * fold_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreCase(t *testing.T) {
+	dialect := *DefaultDialect
+	dialect.IgnoreCase = true
+
+	var warnings []string
+	dialect.Warning = func(l ParseErrorLine) {
+		warnings = append(warnings, l.Reason)
+	}
+
+	const s = "[Boot Loader]\nTimeout=30\n[boot loader]\ntimeout=10\nDefault=a\n"
+	conf, err := dialect.Read(strings.NewReader(s))
+	if nil != err {
+		t.Fatal(err)
+	}
+	expected := Config{"Boot Loader": Section{"Timeout": "10", "Default": "a"}}
+	if !reflect.DeepEqual(expected, conf) || 2 != len(warnings) {
+		t.Error(conf, warnings)
+	}
+
+	tconf, err := dialect.ReadTyped(strings.NewReader(s))
+	if nil != err || int64(10) != FoldTypedConfig(tconf).Get("BOOT LOADER.TIMEOUT") {
+		t.Error(tconf, err)
+	}
+
+	buf := bytes.Buffer{}
+	dialect.Write(&buf, conf)
+	if "[Boot Loader]\nDefault=a\nTimeout=10\n\n" != buf.String() {
+		t.Error(buf.String())
+	}
+
+	doc, err := dialect.ReadDocument(strings.NewReader(s))
+	if nil != err {
+		t.Fatal(err)
+	}
+	doc.Set("BOOT LOADER.default", "b")
+	if "b" != doc.Get("boot loader.DEFAULT") ||
+		!reflect.DeepEqual(Config{"Boot Loader": Section{"Timeout": "10", "Default": "b"}},
+			doc.Config()) {
+		t.Error(doc.Config())
+	}
+
+	// lookup and duplicate detection use the same folding rule
+	warnings = nil
+	conf, err = dialect.Read(strings.NewReader("key=1\n\u212aEY=2\ns=3\n\u017f=4\n"))
+	if nil != err || !reflect.DeepEqual(Config{"": Section{"key": "2", "s": "3", "\u017f": "4"}}, conf) ||
+		1 != len(warnings) {
+		t.Error(conf, err, warnings)
+	}
+	fconf := FoldConfig(Config{"": Section{"key": "1", "s": "3"}})
+	if "1" != fconf.Get("\u212aey") {
+		t.Error()
+	}
+	if _, ok := fconf.Lookup("\u017f"); ok {
+		t.Error()
+	}
+}
+
+func TestFoldConfig(t *testing.T) {
+	conf := Config{
+		"Section": Section{"Name": "a"},
+		"section": Section{"name": "b"},
+	}
+
+	fconf := FoldConfig(conf)
+	if "b" != fconf.Get("section.name") || "a" != fconf.Get("Section.Name") ||
+		"a" != fconf.Get("Section.NAME") {
+		t.Error()
+	}
+	if _, ok := fconf.Lookup("SECTION.missing"); ok {
+		t.Error()
+	}
+
+	fconf.Set("SECTION.NAME", "c")
+	fconf.Set("Other.Key", "d")
+	fconf.Set("other.KEY", "e")
+	if "c" != conf["Section"]["Name"] || "b" != conf["section"]["name"] ||
+		1 != len(conf["Section"]) || "e" != conf["Other"]["Key"] {
+		t.Error(conf)
+	}
+
+	fconf.Delete("OTHER.key")
+	if _, ok := conf["Other"]; ok {
+		t.Error(conf)
+	}
+
+	tconf := TypedConfig{"Server": TypedSection{"Port": int64(80)}}
+	ftconf := FoldTypedConfig(tconf)
+	if int64(80) != ftconf.Get("server.port") {
+		t.Error()
+	}
+	ftconf.Set("SERVER.PORT", int64(8080))
+	if int64(8080) != tconf["Server"]["Port"] || 1 != len(tconf["Server"]) {
+		t.Error(tconf)
+	}
+	ftconf.Delete("server.port")
+	if 0 != len(tconf) {
+		t.Error(tconf)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * include.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// readState contains the state of a read operation across included files.
+type readState struct {
+	stack []string
+	errs  []ParseErrorLine
+	fn    func(file, sect, name string, valu interface{})
+	key   []byte
+}
+
+func (dialect *Dialect) include(state *readState, path string, pattern string) error {
+	if !filepath.IsAbs(pattern) && "" != path {
+		pattern = filepath.Join(filepath.Dir(path), pattern)
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if nil != err {
+		return errors.New(fmt.Sprintf("invalid include pattern %s", pattern), err, ErrConfig)
+	}
+	if 0 == len(paths) && !strings.ContainsAny(pattern, `*?[`) {
+		// not a glob pattern; report the missing file
+		paths = []string{pattern}
+	}
+
+	for _, p := range paths {
+		err = dialect.includeFile(state, p)
+		if nil != err {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dialect *Dialect) includeFile(state *readState, path string) error {
+	abs, err := filepath.Abs(path)
+	if nil != err {
+		return errors.New(fmt.Sprintf("cannot include %s", path), err, ErrConfig)
+	}
+	for _, s := range state.stack {
+		if s == abs {
+			return errors.New(fmt.Sprintf("include cycle at %s", path), nil, ErrConfig)
+		}
+	}
+
+	file, err := os.Open(path)
+	if nil != err {
+		return errors.New(fmt.Sprintf("cannot include %s", path), err, ErrConfig)
+	}
+	defer file.Close()
+
+	state.stack = append(state.stack, abs)
+	defer func() {
+		state.stack = state.stack[:len(state.stack)-1]
+	}()
+
+	return dialect.readItems(state, file, path)
+}
+
+// ReadFile reads a configuration from the named file.
+func (dialect *Dialect) ReadFile(path string) (Config, error) {
+	file, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer file.Close()
+
+	return dialect.read(file, path)
+}
+
+// ReadTypedFile reads a typed configuration from the named file.
+func (dialect *Dialect) ReadTypedFile(path string) (TypedConfig, error) {
+	file, err := os.Open(path)
+	if nil != err {
+		return nil, err
+	}
+	defer file.Close()
+
+	return dialect.readTyped(file, path, nil)
+}
@@ -0,0 +1,218 @@
+/*
+ * interpolate.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// interpolator resolves references within property values.
+type interpolator struct {
+	lookup func(k string) (valu string, expand bool, ok bool)
+	done   map[string]string
+	active map[string]bool
+}
+
+func newInterpolator(lookup func(k string) (string, bool, bool)) *interpolator {
+	return &interpolator{
+		lookup: lookup,
+		done:   map[string]string{},
+		active: map[string]bool{},
+	}
+}
+
+func (ip *interpolator) resolve(k string) (string, error) {
+	if v, ok := ip.done[k]; ok {
+		return v, nil
+	}
+	if ip.active[k] {
+		return "", errors.New(fmt.Sprintf("interpolation cycle at %s", k), nil, ErrConfig)
+	}
+
+	v, expand, ok := ip.lookup(k)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("undefined reference %s", k), nil, ErrConfig)
+	}
+	if expand {
+		ip.active[k] = true
+		var err error
+		v, err = ip.expand(v)
+		delete(ip.active, k)
+		if nil != err {
+			return "", errors.New(fmt.Sprintf("cannot interpolate %s", k), err, ErrConfig)
+		}
+	}
+
+	ip.done[k] = v
+	return v, nil
+}
+
+func (ip *interpolator) expand(s string) (string, error) {
+	if -1 == strings.IndexByte(s, '$') {
+		return s, nil
+	}
+
+	buf := bytes.Buffer{}
+	for i := 0; len(s) > i; i++ {
+		c := s[i]
+		if '$' != c || len(s) == i+1 {
+			buf.WriteByte(c)
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			buf.WriteByte('$')
+			i++
+		case '{':
+			j := strings.IndexByte(s[i+2:], '}')
+			if -1 == j {
+				return "", errors.New("unterminated reference", nil, ErrConfig)
+			}
+			ref := s[i+2 : i+2+j]
+			if strings.HasPrefix(ref, "env:") {
+				buf.WriteString(os.Getenv(ref[4:]))
+			} else {
+				v, err := ip.resolve(ref)
+				if nil != err {
+					return "", err
+				}
+				buf.WriteString(v)
+			}
+			i += 2 + j
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// Interpolate resolves references within the property values of the
+// configuration.
+//
+// A reference of the form ${SECTION.PROPNAME} is replaced by the value of
+// the named property; a reference of the form ${env:NAME} is replaced by
+// the value of the named environment variable. The sequence $$ is replaced
+// by a single $. References to undefined properties and reference cycles
+// are reported as errors.
+func (conf Config) Interpolate() error {
+	ip := newInterpolator(func(k string) (string, bool, bool) {
+		s, n := splitName(k)
+		v, ok := conf[s][n]
+		return v, true, ok
+	})
+
+	for sect, smap := range conf {
+		for name := range smap {
+			v, err := ip.resolve(propertyName(sect, name))
+			if nil != err {
+				return err
+			}
+			smap[name] = v
+		}
+	}
+
+	return nil
+}
+
+// Interpolate resolves references within the string property values of
+// the typed configuration. Non-string properties may be referenced, but
+// they are not modified. See Config.Interpolate for the reference syntax.
+func (conf TypedConfig) Interpolate() error {
+	ip := newInterpolator(func(k string) (string, bool, bool) {
+		s, n := splitName(k)
+		v, ok := conf[s][n]
+		if !ok {
+			return "", false, false
+		}
+		if s, ok := v.(string); ok {
+			return s, true, true
+		}
+		return formatTyped(v), false, true
+	})
+
+	for sect, smap := range conf {
+		for name, valu := range smap {
+			if _, ok := valu.(string); !ok {
+				continue
+			}
+			v, err := ip.resolve(propertyName(sect, name))
+			if nil != err {
+				return err
+			}
+			smap[name] = v
+		}
+	}
+
+	return nil
+}
+
+// interpolateRaw resolves references within a typed configuration that
+// contains raw values as read by ReadFunc and then types the values.
+func (dialect *Dialect) interpolateRaw(conf TypedConfig) error {
+	ip := newInterpolator(func(k string) (string, bool, bool) {
+		s, n := splitName(k)
+		v, ok := conf[s][n]
+		if !ok {
+			return "", false, false
+		}
+		switch r := v.(type) {
+		case nil:
+			return "true", false, true
+		case string:
+			return itemString(r), true, true
+		default:
+			// lists cannot be referenced
+			return "", false, false
+		}
+	})
+
+	for sect, smap := range conf {
+		for name, valu := range smap {
+			if list, ok := valu.([]interface{}); ok {
+				for i, elem := range list {
+					v, err := ip.expand(itemString(elem))
+					if nil != err {
+						return errors.New(
+							fmt.Sprintf("cannot interpolate %s", propertyName(sect, name)),
+							err, ErrConfig)
+					}
+					if q := elem.(string); 0 < len(q) && '"' == q[0] {
+						list[i] = v
+					} else {
+						list[i] = dialect.inferValue(v)
+					}
+				}
+				continue
+			}
+			v, err := ip.resolve(propertyName(sect, name))
+			if nil != err {
+				return err
+			}
+			if nil == valu {
+				smap[name] = true
+			} else if q := valu.(string); 0 < len(q) && '"' == q[0] {
+				smap[name] = v
+			} else {
+				smap[name] = dialect.inferValue(v)
+			}
+		}
+	}
+
+	return nil
+}
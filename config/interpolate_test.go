@@ -0,0 +1,112 @@
+/*
+ * interpolate_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+var interpolateDialect = &Dialect{
+	AssignChars:   "=:",
+	CommentChars:  ";#",
+	ReadEmptyKeys: true,
+	Interpolate:   true,
+}
+
+const testInterpolate = `
+name=app
+debug
+[paths]
+root=/opt/${name}
+logdir=${paths.root}/logs
+home=${env:CONFIG_TEST_HOME}
+price="$$5 ${paths.root}"
+port=${net.port}
+flag=${debug}
+[net]
+port=8080
+`
+
+func TestInterpolate(t *testing.T) {
+	os.Setenv("CONFIG_TEST_HOME", "/home/test")
+	defer os.Unsetenv("CONFIG_TEST_HOME")
+
+	conf, err := interpolateDialect.Read(strings.NewReader(testInterpolate))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "/opt/app/logs" != conf.Get("paths.logdir") ||
+		"/home/test" != conf.Get("paths.home") ||
+		"$5 /opt/app" != conf.Get("paths.price") ||
+		"8080" != conf.Get("paths.port") {
+		t.Error(conf)
+	}
+
+	tconf, err := interpolateDialect.ReadTyped(strings.NewReader(testInterpolate))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "/opt/app/logs" != tconf.Get("paths.logdir") ||
+		"$5 /opt/app" != tconf.Get("paths.price") ||
+		int64(8080) != tconf.Get("paths.port") ||
+		true != tconf.Get("paths.flag") ||
+		true != tconf.Get("debug") {
+		t.Error(tconf)
+	}
+
+	conf, err = Read(strings.NewReader(testInterpolate))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "${paths.root}/logs" != conf.Get("paths.logdir") {
+		t.Error()
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	conf := Config{}
+	conf.Set("a", "${b}")
+	conf.Set("b", "${s.c}")
+	conf.Set("s.c", "${a}")
+	err := conf.Interpolate()
+	if nil == err || !strings.Contains(err.Error(), "interpolation cycle") {
+		t.Error(err)
+	}
+
+	conf = Config{}
+	conf.Set("a", "${undefined}")
+	err = conf.Interpolate()
+	if nil == err || !strings.Contains(err.Error(), "undefined reference undefined") {
+		t.Error(err)
+	}
+
+	conf = Config{}
+	conf.Set("a", "${b")
+	err = conf.Interpolate()
+	if nil == err || !strings.Contains(err.Error(), "unterminated reference") {
+		t.Error(err)
+	}
+
+	tconf := TypedConfig{}
+	tconf.Set("n", int64(42))
+	tconf.Set("s", "n=${n}")
+	err = tconf.Interpolate()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "n=42" != tconf.Get("s") || int64(42) != tconf.Get("n") {
+		t.Error()
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * json.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// ReadJSON reads a typed configuration from a JSON object.
+//
+// Members of the top-level object whose values are objects become sections;
+// nested objects become sections whose names are joined with dots (so that
+// {"remote": {"origin": {...}}} becomes the section "remote.origin"). Other
+// members become properties of the unnamed section. Integral numbers are
+// read as int64, other numbers as float64, arrays as []interface{}; null
+// values are ignored.
+func ReadJSON(reader io.Reader) (TypedConfig, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+
+	var obj map[string]interface{}
+	err := decoder.Decode(&obj)
+	if nil != err {
+		return nil, errors.New("cannot read JSON", err, ErrConfig)
+	}
+
+	conf := TypedConfig{}
+	err = readJSONObject(conf, "", obj)
+	if nil != err {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func readJSONObject(conf TypedConfig, sect string, obj map[string]interface{}) error {
+	for name, valu := range obj {
+		if o, ok := valu.(map[string]interface{}); ok {
+			if "" == name || -1 != strings.IndexByte(name, '.') {
+				return errors.New(
+					fmt.Sprintf("invalid section name %q", joinSection(sect, name)),
+					nil, ErrConfig)
+			}
+			s := joinSection(sect, name)
+			if _, ok := conf[s]; !ok {
+				conf[s] = TypedSection{}
+			}
+			err := readJSONObject(conf, s, o)
+			if nil != err {
+				return err
+			}
+			continue
+		}
+		if nil == valu {
+			continue
+		}
+		v, err := jsonValue(valu)
+		if nil != err {
+			return errors.New(
+				fmt.Sprintf("cannot read %s", propertyName(sect, name)), err, ErrConfig)
+		}
+		tsmap := conf[sect]
+		if nil == tsmap {
+			tsmap = TypedSection{}
+			conf[sect] = tsmap
+		}
+		tsmap[name] = v
+	}
+	return nil
+}
+
+func jsonValue(valu interface{}) (interface{}, error) {
+	switch v := valu.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); nil == err {
+			return i, nil
+		}
+		return strconv.ParseFloat(string(v), 64)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, elem := range v {
+			e, err := jsonValue(elem)
+			if nil != err {
+				return nil, err
+			}
+			list[i] = e
+		}
+		return list, nil
+	case map[string]interface{}:
+		return nil, fmt.Errorf("objects are not supported within arrays")
+	default:
+		return v, nil
+	}
+}
+
+// WriteJSON writes a typed configuration as a JSON object. It is the
+// inverse of ReadJSON. Floating point numbers are written with a decimal
+// point so that they are read back as float64; values other than strings,
+// numbers, booleans and lists are written as strings.
+func WriteJSON(writer io.Writer, conf TypedConfig) error {
+	obj := map[string]interface{}{}
+	for sect, tsmap := range conf {
+		o := obj
+		if "" != sect {
+			for _, part := range strings.Split(sect, ".") {
+				m, ok := o[part].(map[string]interface{})
+				if !ok {
+					if _, ok := o[part]; ok {
+						return errors.New(
+							fmt.Sprintf("section %s conflicts with a property", sect),
+							nil, ErrConfig)
+					}
+					m = map[string]interface{}{}
+					o[part] = m
+				}
+				o = m
+			}
+		}
+		for name, valu := range tsmap {
+			if _, ok := o[name].(map[string]interface{}); ok {
+				return errors.New(
+					fmt.Sprintf("property %s conflicts with a section", propertyName(sect, name)),
+					nil, ErrConfig)
+			}
+			o[name] = jsonTyped(valu)
+		}
+	}
+
+	buf, err := json.MarshalIndent(obj, "", "    ")
+	if nil != err {
+		return errors.New("cannot write JSON", err, ErrConfig)
+	}
+	buf = append(buf, '\n')
+
+	_, err = writer.Write(buf)
+	return err
+}
+
+func jsonTyped(valu interface{}) interface{} {
+	if list, ok := listValue(valu); ok {
+		res := make([]interface{}, len(list))
+		for i, elem := range list {
+			res[i] = jsonTyped(elem)
+		}
+		return res
+	}
+	switch v := valu.(type) {
+	case string, bool,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		return jsonTyped(float64(v))
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return formatTyped(v)
+		}
+		if v == math.Trunc(v) && 1e15 > math.Abs(v) {
+			return json.Number(formatFloat(v, 64))
+		}
+		return v
+	default:
+		return formatTyped(v)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * json_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadJSON(t *testing.T) {
+	conf, err := ReadJSON(strings.NewReader(`{
+		"name": "app",
+		"count": 42,
+		"ratio": 1.0,
+		"debug": true,
+		"none": null,
+		"tags": ["a", 1, 2.5],
+		"server": {"port": 8080, "tls": {"enabled": false}},
+		"empty": {}
+	}`))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	expected := TypedConfig{
+		"": TypedSection{
+			"name":  "app",
+			"count": int64(42),
+			"ratio": 1.0,
+			"debug": true,
+			"tags":  []interface{}{"a", int64(1), 2.5},
+		},
+		"server":     TypedSection{"port": int64(8080)},
+		"server.tls": TypedSection{"enabled": false},
+		"empty":      TypedSection{},
+	}
+	if !reflect.DeepEqual(expected, conf) {
+		t.Error(conf)
+	}
+
+	_, err = ReadJSON(strings.NewReader(`{"a": [{"b": 1}]}`))
+	if nil == err {
+		t.Error()
+	}
+	_, err = ReadJSON(strings.NewReader(`[1, 2]`))
+	if nil == err {
+		t.Error()
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	conf := TypedConfig{
+		"": TypedSection{
+			"name":  "app",
+			"ratio": 1.0,
+		},
+		"server": TypedSection{
+			"port":    8080,
+			"timeout": 5 * time.Second,
+			"hosts":   []string{"a", "b"},
+		},
+		"server.tls": TypedSection{"enabled": false},
+	}
+
+	buf := bytes.Buffer{}
+	err := WriteJSON(&buf, conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	expected := `{
+    "name": "app",
+    "ratio": 1.0,
+    "server": {
+        "hosts": [
+            "a",
+            "b"
+        ],
+        "port": 8080,
+        "timeout": "5s",
+        "tls": {
+            "enabled": false
+        }
+    }
+}
+`
+	if expected != buf.String() {
+		t.Error(buf.String())
+	}
+
+	conf2, err := ReadJSON(&buf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1.0 != conf2.Get("ratio") || int64(8080) != conf2.Get("server.port") ||
+		"5s" != conf2.Get("server.timeout") || false != conf2.Get("server.tls.enabled") {
+		t.Error(conf2)
+	}
+
+	err = WriteJSON(&buf, TypedConfig{"": TypedSection{"a": 1}, "a": TypedSection{}})
+	if nil == err {
+		t.Error()
+	}
+}
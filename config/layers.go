@@ -0,0 +1,163 @@
+/*
+ * layers.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"os"
+	"sync"
+)
+
+// Layers is used to stack multiple configurations, such as a system, a user
+// and a project configuration. Properties in layers added later override
+// properties in layers added earlier.
+//
+// When using Get, Origin to query properties the property names
+// follow the syntax SECTION.PROPNAME
+type Layers struct {
+	layers []layer
+	mux    sync.Mutex
+}
+
+type layer struct {
+	conf   TypedConfig
+	name   string
+	origin map[string]string
+}
+
+// Add adds a configuration as a new layer. The name parameter is reported
+// by Origin for properties in this layer.
+func (self *Layers) Add(name string, conf Config) {
+	self.AddTyped(name, conf.Typed())
+}
+
+// AddTyped adds a typed configuration as a new layer. The name parameter
+// is reported by Origin for properties in this layer.
+func (self *Layers) AddTyped(name string, conf TypedConfig) {
+	self.mux.Lock()
+	defer self.mux.Unlock()
+	self.layers = append(self.layers, layer{conf: conf, name: name})
+}
+
+// AddFile reads a typed configuration from the named file and adds it as
+// a new layer. Origin reports the file that each property was read from,
+// which may be an included file.
+func (self *Layers) AddFile(dialect *Dialect, path string) error {
+	if nil == dialect {
+		dialect = DefaultDialect
+	}
+
+	file, err := os.Open(path)
+	if nil != err {
+		return err
+	}
+	defer file.Close()
+
+	origin := map[string]string{}
+	conf, err := dialect.readTyped(file, path, origin)
+	if nil != err {
+		return err
+	}
+
+	self.mux.Lock()
+	defer self.mux.Unlock()
+	self.layers = append(self.layers, layer{conf: conf, name: path, origin: origin})
+
+	return nil
+}
+
+// Get gets the effective value of a property.
+func (self *Layers) Get(k string) interface{} {
+	v, _ := self.lookup(k)
+	return v
+}
+
+// Origin returns the name of the layer (or file) that provides the
+// effective value of a property. It returns the empty string if no layer
+// contains the property.
+func (self *Layers) Origin(k string) string {
+	_, o := self.lookup(k)
+	return o
+}
+
+func (self *Layers) lookup(k string) (interface{}, string) {
+	self.mux.Lock()
+	defer self.mux.Unlock()
+
+	s, n := splitName(k)
+	for i := len(self.layers) - 1; 0 <= i; i-- {
+		l := &self.layers[i]
+		if v, ok := l.conf[s][n]; ok {
+			if o, ok := l.origin[k]; ok {
+				return v, o
+			}
+			return v, l.name
+		}
+	}
+	return nil, ""
+}
+
+// TypedConfig returns the effective typed configuration.
+func (self *Layers) TypedConfig() TypedConfig {
+	self.mux.Lock()
+	defer self.mux.Unlock()
+
+	confs := make([]TypedConfig, len(self.layers))
+	for i := range self.layers {
+		confs[i] = self.layers[i].conf
+	}
+	return MergeTyped(confs...)
+}
+
+// Config returns the effective configuration.
+func (self *Layers) Config() Config {
+	return self.TypedConfig().Untyped()
+}
+
+// Merge merges multiple configurations into a new configuration.
+// Properties in later configurations override properties in earlier ones.
+func Merge(confs ...Config) Config {
+	res := Config{}
+	for _, conf := range confs {
+		for sect, smap := range conf {
+			rsmap, ok := res[sect]
+			if !ok {
+				rsmap = Section{}
+				res[sect] = rsmap
+			}
+			for k, v := range smap {
+				rsmap[k] = v
+			}
+		}
+	}
+	return res
+}
+
+// MergeTyped merges multiple typed configurations into a new typed
+// configuration. Properties in later configurations override properties
+// in earlier ones.
+func MergeTyped(confs ...TypedConfig) TypedConfig {
+	res := TypedConfig{}
+	for _, conf := range confs {
+		for sect, smap := range conf {
+			rsmap, ok := res[sect]
+			if !ok {
+				rsmap = TypedSection{}
+				res[sect] = rsmap
+			}
+			for k, v := range smap {
+				rsmap[k] = v
+			}
+		}
+	}
+	return res
+}
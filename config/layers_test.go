@@ -0,0 +1,151 @@
+/*
+ * layers_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if nil != err {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		err = ioutil.WriteFile(path, []byte(data), 0644)
+		if nil != err {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+var includeDialect = &Dialect{
+	AssignChars:   "=:",
+	CommentChars:  ";#",
+	ReadEmptyKeys: true,
+	IncludeKey:    "include",
+}
+
+func TestInclude(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"main.conf":      "a=1\ninclude=conf.d/*.conf\n[sect]\nb=2\ninclude=other.conf\n",
+		"conf.d/1.conf":  "a=10\n[sect]\nc=3\n",
+		"conf.d/2.conf":  "[sect]\nc=4\n",
+		"other.conf":     "d=5\n",
+		"cycle.conf":     "include=cycle2.conf\n",
+		"cycle2.conf":    "include=cycle.conf\n",
+		"missing.conf":   "include=nonexistent.conf\n",
+		"noinclude.conf": "include=other.conf\n",
+	})
+	defer os.RemoveAll(dir)
+
+	conf, err := includeDialect.ReadFile(filepath.Join(dir, "main.conf"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	expected := Config{
+		"":     Section{"a": "10", "d": "5"},
+		"sect": Section{"b": "2", "c": "4"},
+	}
+	if !reflect.DeepEqual(expected, conf) {
+		t.Error(conf)
+	}
+
+	_, err = includeDialect.ReadFile(filepath.Join(dir, "cycle.conf"))
+	if nil == err || !strings.Contains(err.Error(), "include cycle") {
+		t.Error(err)
+	}
+
+	_, err = includeDialect.ReadFile(filepath.Join(dir, "missing.conf"))
+	if nil == err || !errors.HasAttachment(err, ErrConfig) {
+		t.Error(err)
+	}
+
+	conf, err = ReadFile(filepath.Join(dir, "noinclude.conf"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "other.conf" != conf.Get("include") {
+		t.Error()
+	}
+}
+
+func TestLayers(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"user.conf":  "[sect]\nb=2\ninclude=extra.conf\n",
+		"extra.conf": "[sect]\nc=true\n",
+	})
+	defer os.RemoveAll(dir)
+
+	layers := Layers{}
+	layers.Add("system", Config{"sect": Section{"a": "1", "b": "1"}})
+	err := layers.AddFile(includeDialect, filepath.Join(dir, "user.conf"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	layers.AddTyped("flags", TypedConfig{"": TypedSection{"verbose": true}})
+
+	if "1" != layers.Get("sect.a") || "system" != layers.Origin("sect.a") {
+		t.Error()
+	}
+	if int64(2) != layers.Get("sect.b") ||
+		filepath.Join(dir, "user.conf") != layers.Origin("sect.b") {
+		t.Error()
+	}
+	if true != layers.Get("sect.c") ||
+		filepath.Join(dir, "extra.conf") != layers.Origin("sect.c") {
+		t.Error()
+	}
+	if true != layers.Get("verbose") || "flags" != layers.Origin("verbose") {
+		t.Error()
+	}
+	if nil != layers.Get("sect.none") || "" != layers.Origin("sect.none") {
+		t.Error()
+	}
+
+	expected := Config{
+		"":     Section{"verbose": "true"},
+		"sect": Section{"a": "1", "b": "2", "c": "true"},
+	}
+	if !reflect.DeepEqual(expected, layers.Config()) {
+		t.Error(layers.Config())
+	}
+}
+
+func TestMerge(t *testing.T) {
+	c1 := Config{"": Section{"a": "1"}, "s": Section{"b": "1"}}
+	c2 := Config{"s": Section{"b": "2", "c": "2"}}
+	expected := Config{"": Section{"a": "1"}, "s": Section{"b": "2", "c": "2"}}
+	if !reflect.DeepEqual(expected, Merge(c1, c2)) {
+		t.Error()
+	}
+	if "1" != c1.Get("s.b") {
+		t.Error()
+	}
+
+	t1 := TypedConfig{"s": TypedSection{"b": int64(1)}}
+	t2 := TypedConfig{"s": TypedSection{"b": true}}
+	if true != MergeTyped(t1, t2).Get("s.b") {
+		t.Error()
+	}
+}
@@ -0,0 +1,337 @@
+/*
+ * marshal.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Unmarshal stores a configuration into the struct pointed to by v.
+//
+// The conf parameter may be a Config or a TypedConfig. Struct fields that
+// are themselves structs are mapped to sections; other fields are mapped to
+// properties. Properties in the top level struct are read from the unnamed
+// section; structs nested within sections are mapped to sections named
+// SECTION.SUBSECTION.
+//
+// The name of a section or property is the field name, unless overridden
+// using a `config:"name"` tag. A tag of `config:"-"` causes the field to be
+// ignored. A `default:"value"` tag supplies a value for properties that are
+// missing from the configuration.
+//
+// Supported field types are strings, booleans, signed and unsigned integers,
+// floating point numbers, time.Duration, slices of these types, pointers
+// to these types and types that implement encoding.TextUnmarshaler. Slices
+// may be read from lists or from comma-separated strings.
+func Unmarshal(conf interface{}, v interface{}) error {
+	var tconf TypedConfig
+	switch c := conf.(type) {
+	case TypedConfig:
+		tconf = c
+	case Config:
+		tconf = c.Typed()
+	default:
+		return errors.New(fmt.Sprintf("cannot unmarshal from %T", conf), nil, ErrConfig)
+	}
+
+	rv := reflect.ValueOf(v)
+	if reflect.Ptr != rv.Kind() || rv.IsNil() || reflect.Struct != rv.Elem().Kind() {
+		return errors.New(fmt.Sprintf("cannot unmarshal into %T", v), nil, ErrConfig)
+	}
+
+	return unmarshalStruct(tconf, "", rv.Elem())
+}
+
+// Marshal creates a typed configuration from the struct v (or pointer to
+// struct). See Unmarshal for how struct fields are mapped to sections and
+// properties. Fields whose type implements encoding.TextMarshaler are
+// stored as strings.
+func Marshal(v interface{}) (TypedConfig, error) {
+	rv := reflect.ValueOf(v)
+	for reflect.Ptr == rv.Kind() && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if reflect.Struct != rv.Kind() {
+		return nil, errors.New(fmt.Sprintf("cannot marshal %T", v), nil, ErrConfig)
+	}
+
+	conf := TypedConfig{}
+	err := marshalStruct(conf, "", rv)
+	if nil != err {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func propertyName(sect, name string) string {
+	if "" == sect {
+		return name
+	}
+	return sect + "." + name
+}
+
+func fieldName(field reflect.StructField) string {
+	if "" != field.PkgPath {
+		return "" // unexported
+	}
+	name := field.Tag.Get("config")
+	if i := strings.IndexByte(name, ','); -1 != i {
+		name = name[:i]
+	}
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
+
+func isSectionType(t reflect.Type) bool {
+	return reflect.Struct == t.Kind() &&
+		!reflect.PtrTo(t).Implements(textUnmarshalerType) &&
+		!t.Implements(textMarshalerType)
+}
+
+func unmarshalStruct(conf TypedConfig, sect string, sv reflect.Value) error {
+	st := sv.Type()
+	for i := 0; st.NumField() > i; i++ {
+		field := st.Field(i)
+		name := fieldName(field)
+		if "" == name {
+			continue
+		}
+
+		fv := sv.Field(i)
+		if isSectionType(field.Type) {
+			err := unmarshalStruct(conf, propertyName(sect, name), fv)
+			if nil != err {
+				return err
+			}
+			continue
+		}
+
+		valu, ok := conf[sect][name]
+		if !ok {
+			if valu, ok = field.Tag.Lookup("default"); !ok {
+				continue
+			}
+		}
+
+		err := setValue(fv, valu)
+		if nil != err {
+			return errors.New(
+				fmt.Sprintf("cannot unmarshal %s", propertyName(sect, name)), err, ErrConfig)
+		}
+	}
+
+	return nil
+}
+
+func setValue(fv reflect.Value, valu interface{}) error {
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		s, err := toString(valu)
+		if nil != err {
+			return err
+		}
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if durationType == fv.Type() {
+		d, err := toDuration(valu)
+		if nil != err {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		s, err := toString(valu)
+		if nil != err {
+			return err
+		}
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := toBool(valu)
+		if nil != err {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt(valu)
+		if nil != err {
+			return err
+		}
+		if fv.OverflowInt(i) {
+			return fmt.Errorf("value %d out of range", i)
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := toUint(valu)
+		if nil != err {
+			return err
+		}
+		if fv.OverflowUint(u) {
+			return fmt.Errorf("value %d out of range", u)
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(valu)
+		if nil != err {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		list, err := toList(valu)
+		if nil != err {
+			return err
+		}
+		sv := reflect.MakeSlice(fv.Type(), len(list), len(list))
+		for i, elem := range list {
+			err = setValue(sv.Index(i), elem)
+			if nil != err {
+				return err
+			}
+		}
+		fv.Set(sv)
+	case reflect.Ptr:
+		pv := reflect.New(fv.Type().Elem())
+		err := setValue(pv.Elem(), valu)
+		if nil != err {
+			return err
+		}
+		fv.Set(pv)
+	case reflect.Interface:
+		if nil == valu {
+			return fmt.Errorf("missing value")
+		}
+		rv := reflect.ValueOf(valu)
+		if !rv.Type().AssignableTo(fv.Type()) {
+			return fmt.Errorf("cannot assign %T to %s", valu, fv.Type())
+		}
+		fv.Set(rv)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+
+	return nil
+}
+
+func marshalStruct(conf TypedConfig, sect string, sv reflect.Value) error {
+	st := sv.Type()
+	for i := 0; st.NumField() > i; i++ {
+		field := st.Field(i)
+		name := fieldName(field)
+		if "" == name {
+			continue
+		}
+
+		fv := sv.Field(i)
+		if isSectionType(field.Type) {
+			err := marshalStruct(conf, propertyName(sect, name), fv)
+			if nil != err {
+				return err
+			}
+			continue
+		}
+
+		valu, ok, err := getValue(fv)
+		if nil != err {
+			return errors.New(
+				fmt.Sprintf("cannot marshal %s", propertyName(sect, name)), err, ErrConfig)
+		}
+		if !ok {
+			continue
+		}
+
+		smap, ok := conf[sect]
+		if !ok {
+			smap = TypedSection{}
+			conf[sect] = smap
+		}
+		smap[name] = valu
+	}
+
+	return nil
+}
+
+func getValue(fv reflect.Value) (interface{}, bool, error) {
+	// sizes and times are written in canonical forms by WriteTyped
+	switch v := fv.Interface().(type) {
+	case Size, time.Time:
+		return v, true, nil
+	}
+
+	if fv.Type().Implements(textMarshalerType) {
+		if reflect.Ptr == fv.Kind() && fv.IsNil() {
+			return nil, false, nil
+		}
+		b, err := fv.Interface().(encoding.TextMarshaler).MarshalText()
+		if nil != err {
+			return nil, false, err
+		}
+		return string(b), true, nil
+	}
+
+	if durationType == fv.Type() {
+		return time.Duration(fv.Int()), true, nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), true, nil
+	case reflect.Bool:
+		return fv.Bool(), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fv.Int(), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fv.Uint(), true, nil
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), true, nil
+	case reflect.Slice:
+		if fv.IsNil() {
+			return nil, false, nil
+		}
+		list := make([]interface{}, fv.Len())
+		for i := range list {
+			elem, _, err := getValue(fv.Index(i))
+			if nil != err {
+				return nil, false, err
+			}
+			list[i] = elem
+		}
+		return list, true, nil
+	case reflect.Ptr, reflect.Interface:
+		if fv.IsNil() {
+			return nil, false, nil
+		}
+		return getValue(fv.Elem())
+	}
+
+	return nil, false, fmt.Errorf("unsupported type %s", fv.Type())
+}
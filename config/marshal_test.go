@@ -0,0 +1,168 @@
+/*
+ * marshal_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+type testServer struct {
+	Host    string        `config:"host"`
+	Port    uint16        `config:"port" default:"8080"`
+	Timeout time.Duration `config:"timeout"`
+	Addr    net.IP        `config:"addr"`
+	Tags    []string      `config:"tags"`
+	Limits  struct {
+		Rate  float64 `config:"rate"`
+		Burst *int    `config:"burst"`
+	} `config:"limits"`
+}
+
+type testSettings struct {
+	Name    string     `config:"name"`
+	Debug   bool       `config:"debug"`
+	Ignored string     `config:"-"`
+	Server  testServer `config:"server"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	conf, err := ReadTyped(strings.NewReader(`
+name=test
+debug
+[server]
+host=localhost
+timeout=30s
+addr=127.0.0.1
+tags=a, b ,c
+[server.limits]
+rate=1.5
+burst=10
+`))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var s testSettings
+	err = Unmarshal(conf, &s)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if "test" != s.Name || !s.Debug || "" != s.Ignored {
+		t.Error()
+	}
+	if "localhost" != s.Server.Host || 8080 != s.Server.Port ||
+		30*time.Second != s.Server.Timeout ||
+		!net.IPv4(127, 0, 0, 1).Equal(s.Server.Addr) ||
+		!reflect.DeepEqual([]string{"a", "b", "c"}, s.Server.Tags) {
+		t.Error()
+	}
+	if 1.5 != s.Server.Limits.Rate || nil == s.Server.Limits.Burst ||
+		10 != *s.Server.Limits.Burst {
+		t.Error()
+	}
+
+	var s2 testSettings
+	sconf, err := Read(strings.NewReader("[server]\nport=443\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	err = Unmarshal(sconf, &s2)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 443 != s2.Server.Port {
+		t.Error()
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	conf := TypedConfig{}
+	conf.Set("server.port", int64(70000))
+
+	var s testSettings
+	err := Unmarshal(conf, &s)
+	if nil == err {
+		t.Fatal()
+	}
+	if !strings.Contains(err.Error(), "server.port") {
+		t.Error(err)
+	}
+	if !errors.HasAttachment(err, ErrConfig) {
+		t.Error()
+	}
+
+	err = Unmarshal(conf, s)
+	if nil == err {
+		t.Error()
+	}
+
+	// float64(math.MaxInt64) and float64(math.MaxUint64) are 2^63 and 2^64
+	if _, err = toInt(float64(math.MaxInt64)); nil == err {
+		t.Error()
+	}
+	if _, err = toUint(float64(math.MaxUint64)); nil == err {
+		t.Error()
+	}
+	if i, err := toInt(float64(-1 << 63)); nil != err || math.MinInt64 != i {
+		t.Error(err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	burst := 5
+	s := testSettings{Name: "test", Debug: true}
+	s.Server.Host = "localhost"
+	s.Server.Port = 80
+	s.Server.Timeout = time.Minute
+	s.Server.Addr = net.IPv4(10, 0, 0, 1)
+	s.Server.Tags = []string{"x", "y"}
+	s.Server.Limits.Rate = 2
+	s.Server.Limits.Burst = &burst
+
+	conf, err := Marshal(&s)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if "test" != conf.Get("name") || true != conf.Get("debug") ||
+		nil != conf.Get("Ignored") {
+		t.Error()
+	}
+	if uint64(80) != conf.Get("server.port") ||
+		time.Minute != conf.Get("server.timeout") ||
+		"10.0.0.1" != conf.Get("server.addr") ||
+		!reflect.DeepEqual([]interface{}{"x", "y"}, conf.Get("server.tags")) {
+		t.Error()
+	}
+	if 2.0 != conf.Get("server.limits.rate") ||
+		int64(5) != conf.Get("server.limits.burst") {
+		t.Error()
+	}
+
+	var s2 testSettings
+	err = Unmarshal(conf, &s2)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, s2) {
+		t.Error()
+	}
+}
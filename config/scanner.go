@@ -0,0 +1,182 @@
+/*
+ * scanner.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// TokenKind is the kind of a token returned by a Scanner.
+type TokenKind int
+
+// The token kinds have the same values as the parser item kinds.
+const (
+	TokenBlank TokenKind = iota
+	TokenComment
+	TokenSectionStart
+	TokenProperty
+	TokenError
+)
+
+var tokenKindNames = []string{"Blank", "Comment", "SectionStart", "Property", "Error"}
+
+func (kind TokenKind) String() string {
+	if 0 <= kind && int(kind) < len(tokenKindNames) {
+		return tokenKindNames[kind]
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(kind))
+}
+
+// Token is a single syntactic element of a configuration file. A token
+// usually corresponds to a single line; property tokens with continuation
+// lines span multiple lines.
+type Token struct {
+	// Kind is the kind of the token.
+	Kind TokenKind
+
+	// Line is the line number of the first line of the token (starting at 1).
+	Line int
+
+	// Column is the column of the first non-blank character of the token
+	// (starting at 1).
+	Column int
+
+	// Raw is the raw text of the token without line terminators.
+	// The lines of multi-line tokens are separated by newlines.
+	Raw string
+
+	// Section is the section name of a SectionStart token or the section
+	// that contains a Property token.
+	Section string
+
+	// Name is the property name of a Property token.
+	Name string
+
+	// Value is the property value of a Property token. Quoted values are
+	// unquoted; continuation lines are joined with newlines.
+	Value string
+
+	// Quoted determines whether the property value was quoted.
+	Quoted bool
+
+	// Empty determines whether the property has no value (i.e. it is an
+	// empty key without an assignment).
+	Empty bool
+
+	// Reason describes why an Error token could not be parsed.
+	Reason string
+}
+
+// Scanner reads the tokens of a configuration file one at a time.
+//
+// Unlike ReadFunc, a Scanner reports section headers, comments, blank lines
+// and lines that cannot be parsed, and it may be abandoned at any point.
+// A Scanner does not process includes, lists, interpolation or encrypted
+// values; properties are reported as they appear in the file.
+type Scanner struct {
+	p   *parser
+	tok Token
+}
+
+// NewScanner creates a scanner that reads from the supplied reader.
+func (dialect *Dialect) NewScanner(reader io.Reader) *Scanner {
+	return &Scanner{p: dialect.newParser(reader)}
+}
+
+// Scan advances the scanner to the next token, which is then available
+// through Token. It returns false when there are no more tokens or an
+// error occurred (see Err).
+func (scanner *Scanner) Scan() bool {
+	it, ok := scanner.p.next()
+	if !ok {
+		scanner.tok = Token{}
+		return false
+	}
+
+	tok := Token{
+		Kind:   TokenKind(it.kind),
+		Line:   it.line,
+		Column: 1 + len(it.raw) - len(strings.TrimLeftFunc(it.raw, unicode.IsSpace)),
+		Raw:    it.raw,
+		Reason: it.errs,
+	}
+	if itemBlank == it.kind {
+		tok.Column = 1
+	}
+	switch it.kind {
+	case itemSection:
+		tok.Section = it.sect
+	case itemProperty:
+		tok.Section = it.sect
+		tok.Name = it.name
+		if v, ok := it.valu.(string); ok {
+			tok.Value = itemString(v)
+			tok.Quoted = 0 < len(v) && '"' == v[0]
+		} else {
+			tok.Empty = true
+		}
+	}
+
+	scanner.tok = tok
+	return true
+}
+
+// Token returns the most recent token read by Scan.
+func (scanner *Scanner) Token() Token {
+	return scanner.tok
+}
+
+// Err returns the first non-EOF error encountered by the scanner.
+func (scanner *Scanner) Err() error {
+	return scanner.p.err()
+}
+
+// FormatToken formats a token using the conventions of the dialect.
+// Section headers are formatted as by Write; properties are formatted with
+// the first of the AssignChars. Quoted values are requoted; unquoted values
+// are written as they are, so that they are read (and typed) the same, unless
+// they span multiple lines. Comments and blank lines are trimmed; error
+// tokens are returned unchanged.
+func (dialect *Dialect) FormatToken(tok Token) string {
+	switch tok.Kind {
+	case TokenBlank:
+		return ""
+	case TokenComment:
+		return strings.TrimSpace(tok.Raw)
+	case TokenSectionStart:
+		return dialect.formatSection(tok.Section)
+	case TokenProperty:
+		name := quote(tok.Name, false)
+		if n := strings.TrimSuffix(tok.Name, "[]"); n != tok.Name && quote(n, false) == n {
+			name = tok.Name
+		}
+		if tok.Empty {
+			return name
+		}
+		valu := tok.Value
+		if tok.Quoted || -1 != strings.IndexByte(valu, '\n') {
+			valu = dialect.formatValue(valu, tok.Quoted)
+		}
+		return name + string(dialect.AssignChars[0]) + valu
+	}
+	return tok.Raw
+}
+
+// NewScanner creates a scanner that reads from the supplied reader
+// using the default dialect.
+func NewScanner(reader io.Reader) *Scanner {
+	return DefaultDialect.NewScanner(reader)
+}
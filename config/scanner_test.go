@@ -0,0 +1,159 @@
+/*
+ * scanner_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	dialect := *DefaultDialect
+	dialect.IndentContinuation = true
+
+	scanner := dialect.NewScanner(strings.NewReader(`; comment
+name = "quoted value"
+flag
+
+  [section]
+multi=line1
+  line2
+[broken
+`))
+
+	var tokens []Token
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Token())
+	}
+	if nil != scanner.Err() {
+		t.Fatal(scanner.Err())
+	}
+
+	expected := []Token{
+		{Kind: TokenComment, Line: 1, Column: 1, Raw: "; comment"},
+		{Kind: TokenProperty, Line: 2, Column: 1, Raw: `name = "quoted value"`,
+			Name: "name", Value: "quoted value", Quoted: true},
+		{Kind: TokenProperty, Line: 3, Column: 1, Raw: "flag", Name: "flag", Empty: true},
+		{Kind: TokenBlank, Line: 4, Column: 1},
+		{Kind: TokenSectionStart, Line: 5, Column: 3, Raw: "  [section]", Section: "section"},
+		{Kind: TokenProperty, Line: 6, Column: 1, Raw: "multi=line1\n  line2",
+			Section: "section", Name: "multi", Value: "line1\nline2"},
+		{Kind: TokenError, Line: 8, Column: 1, Raw: "[broken",
+			Reason: "unterminated section header"},
+	}
+	if !reflect.DeepEqual(expected, tokens) {
+		t.Error(tokens)
+	}
+
+	if "SectionStart" != TokenSectionStart.String() || "TokenKind(9)" != TokenKind(9).String() {
+		t.Error()
+	}
+}
+
+func TestScannerStop(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("a=1\n[s]\nb=2\n"))
+	n := 0
+	for scanner.Scan() {
+		n++
+		if TokenSectionStart == scanner.Token().Kind {
+			break
+		}
+	}
+	if 2 != n || "s" != scanner.Token().Section {
+		t.Error(n)
+	}
+}
+
+func TestFormatToken(t *testing.T) {
+	dialect := *DefaultDialect
+	dialect.Subsections = true
+
+	scanner := dialect.NewScanner(strings.NewReader(`  ; comment  
+name : value
+spaced = hello world
+"quoted"="x"
+flag
+list[] = a
+[remote "origin"]
+token=enc:abc=
+[broken
+`))
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, dialect.FormatToken(scanner.Token()))
+	}
+
+	expected := []string{
+		"; comment",
+		"name=value",
+		"spaced=hello world",
+		`quoted="x"`,
+		"flag",
+		"list[]=a",
+		`[remote "origin"]`,
+		"token=enc:abc=",
+		"[broken",
+	}
+	if !reflect.DeepEqual(expected, lines) {
+		t.Error(lines)
+	}
+}
+
+func TestFormatTokenTyped(t *testing.T) {
+	dialect := *DefaultDialect
+	dialect.IndentContinuation = true
+	dialect.ReadDurations = true
+	dialect.ReadSizes = true
+	dialect.ReadTimes = true
+
+	input := `n = +5
+m: -7
+ratio = 1e+3
+when = 2020-01-01T00:00:00Z
+timeout = 1h30m
+size = 512MiB
+ok = true
+s = "+5"
+spaced = hello world
+multi =
+    line 1
+    line 2
+`
+
+	var buf strings.Builder
+	scanner := dialect.NewScanner(strings.NewReader(input))
+	for scanner.Scan() {
+		buf.WriteString(dialect.FormatToken(scanner.Token()))
+		buf.WriteByte('\n')
+	}
+	if nil != scanner.Err() {
+		t.Fatal(scanner.Err())
+	}
+
+	conf, err := dialect.ReadTyped(strings.NewReader(input))
+	if nil != err {
+		t.Fatal(err)
+	}
+	fconf, err := dialect.ReadTyped(strings.NewReader(buf.String()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, fconf) {
+		t.Error(buf.String())
+	}
+	if int64(5) != fconf.Get("n") || "+5" != fconf.Get("s") || "line 1\nline 2" != fconf.Get("multi") {
+		t.Error(fconf)
+	}
+}
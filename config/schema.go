@@ -0,0 +1,345 @@
+/*
+ * schema.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// Type is the type of a property in a schema.
+type Type int
+
+const (
+	TypeString Type = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeDuration
+	TypeList
+)
+
+var typeNames = []string{"string", "int", "float", "bool", "duration", "list"}
+
+func (t Type) String() string {
+	if 0 <= t && int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// Convert converts a value to the Go type that corresponds to the schema
+// type: string, int64, float64, bool, time.Duration or []interface{}.
+func (t Type) Convert(valu interface{}) (interface{}, error) {
+	switch t {
+	case TypeString:
+		return toString(valu)
+	case TypeInt:
+		return toInt(valu)
+	case TypeFloat:
+		return toFloat(valu)
+	case TypeBool:
+		return toBool(valu)
+	case TypeDuration:
+		return toDuration(valu)
+	case TypeList:
+		return toList(valu)
+	}
+	return nil, fmt.Errorf("unknown type %v", t)
+}
+
+// Property describes a property in a schema.
+type Property struct {
+	// Name is the property name using the syntax SECTION.PROPNAME.
+	Name string
+
+	// Type is the property type.
+	Type Type
+
+	// Default is the value used when the property is missing.
+	// It is ignored when nil.
+	Default interface{}
+
+	// Min and Max limit the range of numeric and duration properties.
+	// They are ignored when nil.
+	Min, Max interface{}
+
+	// Enum lists the allowed values of the property.
+	// It is ignored when empty.
+	Enum []interface{}
+
+	// Required determines whether the property must be present.
+	Required bool
+
+	// Help contains a description of the property.
+	Help string
+}
+
+// Schema declares the properties accepted by a configuration.
+type Schema struct {
+	// Properties lists the properties in the schema.
+	Properties []Property
+
+	// AllowUnknown determines whether properties that are not declared
+	// in the schema are accepted.
+	AllowUnknown bool
+}
+
+// ValidationErrorProp describes a property that failed validation.
+type ValidationErrorProp struct {
+	// Name is the property name using the syntax SECTION.PROPNAME.
+	Name string
+
+	// Reason describes why the property failed validation.
+	Reason string
+}
+
+// ValidationError lists the properties that failed validation.
+type ValidationError struct {
+	Props []ValidationErrorProp
+}
+
+func (err *ValidationError) Error() string {
+	parts := make([]string, len(err.Props))
+	for i, p := range err.Props {
+		parts[i] = fmt.Sprintf("%s: %s", p.Name, p.Reason)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validate validates a typed configuration against the schema.
+//
+// Validate returns a new typed configuration where the properties
+// declared in the schema have been converted to their declared types and
+// missing properties have been set to their defaults. All validation
+// failures are reported in a single error whose cause is a
+// *ValidationError; the returned configuration is valid for all other
+// properties.
+func (schema *Schema) Validate(conf TypedConfig) (TypedConfig, error) {
+	res := TypedConfig{}
+	var props []ValidationErrorProp
+	fail := func(name, format string, args ...interface{}) {
+		props = append(props, ValidationErrorProp{name, fmt.Sprintf(format, args...)})
+	}
+
+	declared := map[string]bool{}
+	for i := range schema.Properties {
+		prop := &schema.Properties[i]
+		declared[prop.Name] = true
+
+		s, n := splitName(prop.Name)
+		valu, ok := conf[s][n]
+		if !ok {
+			if prop.Required {
+				fail(prop.Name, "missing required property")
+				continue
+			}
+			if nil == prop.Default {
+				continue
+			}
+			valu = prop.Default
+		}
+
+		v, err := prop.Type.Convert(valu)
+		if nil != err {
+			fail(prop.Name, "invalid %v value: %v", prop.Type, err)
+			continue
+		}
+		if reason := prop.check(v); "" != reason {
+			fail(prop.Name, "%s", reason)
+			continue
+		}
+
+		res.Set(prop.Name, v)
+	}
+
+	unknown := map[string]bool{}
+	for s, smap := range conf {
+		for n := range smap {
+			if name := propertyName(s, n); !declared[name] {
+				unknown[name] = true
+			}
+		}
+	}
+	for _, name := range sortedNames(unknown) {
+		if !schema.AllowUnknown {
+			fail(name, "unknown property")
+			continue
+		}
+		res.Set(name, conf.Get(name))
+	}
+
+	if 0 != len(props) {
+		return res, errors.New(
+			fmt.Sprintf("%d invalid properties", len(props)),
+			&ValidationError{props}, ErrConfig)
+	}
+
+	return res, nil
+}
+
+// ValidateConfig validates a configuration against the schema.
+// See Validate.
+func (schema *Schema) ValidateConfig(conf Config) (TypedConfig, error) {
+	return schema.Validate(conf.Typed())
+}
+
+// check checks a converted value against the range and enum of a property.
+func (prop *Property) check(v interface{}) string {
+	if nil != prop.Min || nil != prop.Max {
+		f, err := toFloat(v)
+		if nil != err {
+			return "range not applicable to value"
+		}
+		if nil != prop.Min {
+			min, err := prop.bound(prop.Min)
+			if nil != err {
+				return fmt.Sprintf("invalid minimum %s: %v", formatTyped(prop.Min), err)
+			}
+			if f < min {
+				return fmt.Sprintf("value %s less than minimum %s",
+					formatTyped(v), formatTyped(prop.Min))
+			}
+		}
+		if nil != prop.Max {
+			max, err := prop.bound(prop.Max)
+			if nil != err {
+				return fmt.Sprintf("invalid maximum %s: %v", formatTyped(prop.Max), err)
+			}
+			if f > max {
+				return fmt.Sprintf("value %s greater than maximum %s",
+					formatTyped(v), formatTyped(prop.Max))
+			}
+		}
+	}
+
+	if 0 != len(prop.Enum) {
+		for _, e := range prop.Enum {
+			if c, err := prop.Type.Convert(e); nil == err && reflect.DeepEqual(c, v) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("value %s not one of %s", formatTyped(v), prop.enumString())
+	}
+
+	return ""
+}
+
+// bound converts a range bound to the property type and then to float64.
+func (prop *Property) bound(b interface{}) (float64, error) {
+	c, err := prop.Type.Convert(b)
+	if nil != err {
+		return 0, err
+	}
+	return toFloat(c)
+}
+
+func (prop *Property) enumString() string {
+	parts := make([]string, len(prop.Enum))
+	for i, e := range prop.Enum {
+		parts[i] = formatTyped(e)
+	}
+	return strings.Join(parts, "|")
+}
+
+// WriteTemplate writes a commented configuration template for a schema to
+// the supplied writer. Every property is preceded by comments that contain
+// its help text, type and constraints. Properties with defaults are written
+// with their default values; other properties are commented out.
+func (dialect *Dialect) WriteTemplate(writer io.Writer, schema *Schema) error {
+	bufw := bufio.NewWriter(writer)
+	comment := string(dialect.CommentChars[0])
+
+	var sects []string
+	props := map[string][]*Property{}
+	for i := range schema.Properties {
+		prop := &schema.Properties[i]
+		s, _ := splitName(prop.Name)
+		if _, ok := props[s]; !ok {
+			sects = append(sects, s)
+		}
+		props[s] = append(props[s], prop)
+	}
+
+	// properties in the unnamed section must precede all sections
+	for i, s := range sects {
+		if "" == s {
+			copy(sects[1:i+1], sects[:i])
+			sects[0] = ""
+			break
+		}
+	}
+
+	for _, s := range sects {
+		if "" != s {
+			bufw.WriteString(dialect.formatSection(s))
+			bufw.WriteString("\n\n")
+		}
+
+		for _, prop := range props[s] {
+			_, n := splitName(prop.Name)
+			if "" != prop.Help {
+				for _, line := range strings.Split(prop.Help, "\n") {
+					bufw.WriteString(comment + " " + line + "\n")
+				}
+			}
+			bufw.WriteString(comment + " " + prop.describe() + "\n")
+			if nil != prop.Default {
+				if v, err := prop.Type.Convert(prop.Default); nil == err {
+					dialect.writeTypedProperty(bufw, n, v, nil)
+					bufw.WriteByte('\n')
+					continue
+				}
+			}
+			bufw.WriteString(comment)
+			bufw.WriteString(quote(n, false))
+			bufw.WriteByte(dialect.AssignChars[0])
+			bufw.WriteString("\n\n")
+		}
+	}
+
+	return bufw.Flush()
+}
+
+// describe describes the type and constraints of a property.
+func (prop *Property) describe() string {
+	parts := []string{"type: " + prop.Type.String()}
+	if nil != prop.Min || nil != prop.Max {
+		min, max := "", ""
+		if nil != prop.Min {
+			min = formatTyped(prop.Min)
+		}
+		if nil != prop.Max {
+			max = formatTyped(prop.Max)
+		}
+		parts = append(parts, "range: "+min+".."+max)
+	}
+	if 0 != len(prop.Enum) {
+		parts = append(parts, "values: "+prop.enumString())
+	}
+	if prop.Required {
+		parts = append(parts, "required")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteTemplate writes a commented configuration template for a schema to
+// the supplied writer using the default dialect.
+func WriteTemplate(writer io.Writer, schema *Schema) error {
+	return DefaultDialect.WriteTemplate(writer, schema)
+}
@@ -0,0 +1,194 @@
+/*
+ * schema_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+var testSchema = &Schema{
+	Properties: []Property{
+		{Name: "name", Type: TypeString, Required: true, Help: "Application name."},
+		{Name: "server.port", Type: TypeInt, Default: 8080, Min: 1, Max: 65535,
+			Help: "Port to listen on."},
+		{Name: "server.timeout", Type: TypeDuration, Default: "30s"},
+		{Name: "server.mode", Type: TypeString, Default: "fast",
+			Enum: []interface{}{"fast", "safe"}},
+		{Name: "server.ratio", Type: TypeFloat},
+		{Name: "server.debug", Type: TypeBool, Default: false},
+	},
+}
+
+func TestSchemaValidate(t *testing.T) {
+	conf, err := ReadTyped(strings.NewReader(`
+name=app
+[server]
+port=443
+ratio=1
+`))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	res, err := testSchema.Validate(conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	expected := TypedConfig{
+		"": TypedSection{"name": "app"},
+		"server": TypedSection{
+			"port":    int64(443),
+			"timeout": 30 * time.Second,
+			"mode":    "fast",
+			"ratio":   1.0,
+			"debug":   false,
+		},
+	}
+	if !reflect.DeepEqual(expected, res) {
+		t.Error(res)
+	}
+
+	sconf, err := Read(strings.NewReader("name=app\n[server]\nport=80\ndebug=yes\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	_, err = testSchema.ValidateConfig(sconf)
+	if nil == err || !strings.Contains(err.Error(), "server.debug: invalid bool value") {
+		t.Error(err)
+	}
+}
+
+func TestSchemaViolations(t *testing.T) {
+	conf := TypedConfig{}
+	conf.Set("server.port", int64(70000))
+	conf.Set("server.mode", "slow")
+	conf.Set("server.timeout", "forever")
+	conf.Set("server.unknown", true)
+
+	res, err := testSchema.Validate(conf)
+	if nil == err {
+		t.Fatal()
+	}
+	if !errors.HasAttachment(err, ErrConfig) {
+		t.Error()
+	}
+	verr, ok := errors.Cause(err).(*ValidationError)
+	if !ok {
+		t.Fatal()
+	}
+	names := []string{}
+	for _, p := range verr.Props {
+		names = append(names, p.Name)
+	}
+	expected := []string{"name", "server.port", "server.timeout", "server.mode", "server.unknown"}
+	if !reflect.DeepEqual(expected, names) {
+		t.Error(verr)
+	}
+	if false != res.Get("server.debug") {
+		t.Error()
+	}
+
+	schema := *testSchema
+	schema.AllowUnknown = true
+	conf = TypedConfig{}
+	conf.Set("name", "app")
+	conf.Set("other", int64(1))
+	res, err = schema.Validate(conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if int64(1) != res.Get("other") {
+		t.Error()
+	}
+}
+
+func TestSchemaTemplate(t *testing.T) {
+	var buf strings.Builder
+	err := WriteTemplate(&buf, testSchema)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	expected := `; Application name.
+; type: string, required
+;name=
+
+[server]
+
+; Port to listen on.
+; type: int, range: 1..65535
+port=8080
+
+; type: duration
+timeout=30s
+
+; type: string, values: fast|safe
+mode="fast"
+
+; type: float
+;ratio=
+
+; type: bool
+debug=false
+
+`
+	if expected != buf.String() {
+		t.Error(buf.String())
+	}
+
+	conf, err := ReadTyped(strings.NewReader(buf.String()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	conf.Set("name", "app")
+	_, err = testSchema.Validate(conf)
+	if nil != err {
+		t.Error(err)
+	}
+}
+
+func TestSchemaBounds(t *testing.T) {
+	schema := &Schema{
+		Properties: []Property{
+			{Name: "timeout", Type: TypeDuration, Min: "1s", Max: "1m"},
+			{Name: "count", Type: TypeInt, Min: "x"},
+		},
+	}
+
+	conf := TypedConfig{}
+	conf.Set("timeout", "10s")
+	_, err := schema.Validate(conf)
+	if nil != err {
+		t.Error(err)
+	}
+
+	for _, v := range []string{"500ms", "2m"} {
+		conf.Set("timeout", v)
+		_, err = schema.Validate(conf)
+		if nil == err {
+			t.Error(v)
+		}
+	}
+
+	conf = TypedConfig{}
+	conf.Set("count", int64(1))
+	_, err = schema.Validate(conf)
+	if nil == err || !strings.Contains(errors.Cause(err).Error(), "invalid minimum") {
+		t.Error(err)
+	}
+}
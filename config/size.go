@@ -0,0 +1,125 @@
+/*
+ * size.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size is a size in bytes. Sizes are written in a human readable form
+// such as 512MiB.
+type Size int64
+
+var sizeUnits = []struct {
+	name string
+	mult int64
+}{
+	{"PiB", 1 << 50},
+	{"TiB", 1 << 40},
+	{"GiB", 1 << 30},
+	{"MiB", 1 << 20},
+	{"KiB", 1 << 10},
+	{"PB", 1e15},
+	{"TB", 1e12},
+	{"GB", 1e9},
+	{"MB", 1e6},
+	{"KB", 1e3},
+	{"P", 1 << 50},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// String formats a size using the largest binary unit (KiB, MiB, GiB,
+// TiB, PiB) that represents it exactly; other sizes are formatted in bytes.
+func (size Size) String() string {
+	for _, u := range sizeUnits[:5] {
+		if 0 != size && 0 == int64(size)%u.mult {
+			return strconv.FormatInt(int64(size)/u.mult, 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(size), 10) + "B"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (size Size) MarshalText() ([]byte, error) {
+	return []byte(size.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (size *Size) UnmarshalText(text []byte) error {
+	s, err := ParseSize(string(text))
+	if nil != err {
+		return err
+	}
+	*size = s
+	return nil
+}
+
+// ParseSize parses a size. A size is a number followed by an optional
+// unit: B, decimal units (KB, MB, GB, TB, PB) or binary units (KiB, MiB,
+// GiB, TiB, PiB or K, M, G, T, P). Units are case-insensitive; a number
+// without a unit is a size in bytes. Fractional numbers are rounded to
+// the nearest byte.
+func ParseSize(s string) (Size, error) {
+	return parseSize(s, false)
+}
+
+func parseSize(s string, unit bool) (Size, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(c rune) bool {
+		return !('0' <= c && c <= '9' || '.' == c || '-' == c || '+' == c)
+	})
+	if -1 == i {
+		if unit {
+			return 0, fmt.Errorf("missing unit in size %q", s)
+		}
+		i = len(s)
+	}
+
+	num, name := s[:i], strings.TrimSpace(s[i:])
+	mult := int64(1)
+	if "" != name {
+		mult = 0
+		for _, u := range sizeUnits {
+			if strings.EqualFold(u.name, name) {
+				mult = u.mult
+				break
+			}
+		}
+		if 0 == mult {
+			return 0, fmt.Errorf("invalid unit in size %q", s)
+		}
+	}
+
+	if n, err := strconv.ParseInt(num, 10, 64); nil == err {
+		if n > math.MaxInt64/mult || n < math.MinInt64/mult {
+			return 0, fmt.Errorf("size %q out of range", s)
+		}
+		return Size(n * mult), nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if nil != err {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	f = math.Round(f * float64(mult))
+	if math.MaxInt64 <= f || math.MinInt64 > f {
+		return 0, fmt.Errorf("size %q out of range", s)
+	}
+	return Size(f), nil
+}
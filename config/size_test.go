@@ -0,0 +1,163 @@
+/*
+ * size_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSize(t *testing.T) {
+	tests := map[string]Size{
+		"0":       0,
+		"100":     100,
+		"100B":    100,
+		"1KiB":    1024,
+		"1kib":    1024,
+		"1.5 KiB": 1536,
+		"512MiB":  512 << 20,
+		"512M":    512 << 20,
+		"10KB":    10000,
+		"2GB":     2e9,
+		"-1K":     -1024,
+		"1PiB":    1 << 50,
+	}
+	for s, size := range tests {
+		if v, err := ParseSize(s); nil != err || size != v {
+			t.Error(s, v, err)
+		}
+	}
+
+	for _, s := range []string{"", "KiB", "1XB", "1.2.3MB", "9999999PiB"} {
+		if _, err := ParseSize(s); nil == err {
+			t.Error(s)
+		}
+	}
+
+	strs := map[Size]string{
+		0:         "0B",
+		1000:      "1000B",
+		1024:      "1KiB",
+		1536:      "1536B",
+		512 << 20: "512MiB",
+		3 << 40:   "3TiB",
+		-2048:     "-2KiB",
+	}
+	for size, s := range strs {
+		if s != size.String() {
+			t.Error(size, size.String())
+		}
+	}
+}
+
+func TestReadTypedInference(t *testing.T) {
+	dialect := *DefaultDialect
+	dialect.ReadDurations = true
+	dialect.ReadSizes = true
+	dialect.ReadTimes = true
+
+	const s = "timeout=30s\nwait=5m\ncache=512MiB\nbuf=4M\n" +
+		"when=2021-03-04T05:06:07.5Z\nport=8080\nname=\"30s\"\nword=5MB/s\n"
+	conf, err := dialect.ReadTyped(strings.NewReader(s))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2021, 3, 4, 5, 6, 7, 5e8, time.UTC)
+	expected := TypedConfig{
+		"": TypedSection{
+			"timeout": 30 * time.Second,
+			"wait":    5 * time.Minute,
+			"cache":   Size(512 << 20),
+			"buf":     Size(4 << 20),
+			"when":    when,
+			"port":    int64(8080),
+			"name":    "30s",
+			"word":    "5MB/s",
+		},
+	}
+	if !reflect.DeepEqual(expected, conf) {
+		t.Error(conf)
+	}
+
+	buf := bytes.Buffer{}
+	err = dialect.WriteTyped(&buf, conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "buf=4MiB\ncache=512MiB\nname=\"30s\"\nport=8080\ntimeout=30s\n"+
+		"wait=5m0s\nwhen=2021-03-04T05:06:07.5Z\nword=\"5MB/s\"\n\n" != buf.String() {
+		t.Error(buf.String())
+	}
+
+	conf2, err := dialect.ReadTyped(&buf)
+	if nil != err || !reflect.DeepEqual(conf, conf2) {
+		t.Error(conf2, err)
+	}
+
+	conf3, err := ReadTyped(strings.NewReader(s))
+	if nil != err || "30s" != conf3.Get("timeout") || "512MiB" != conf3.Get("cache") {
+		t.Error(conf3, err)
+	}
+}
+
+func TestDurationRoundTrip(t *testing.T) {
+	dialect := *DefaultDialect
+	dialect.ReadDurations = true
+
+	conf := TypedConfig{
+		"": TypedSection{
+			"a": time.Duration(1500),
+			"b": time.Microsecond,
+			"c": time.Duration(999),
+			"d": time.Nanosecond,
+			"e": time.Duration(0),
+		},
+	}
+
+	buf := bytes.Buffer{}
+	err := dialect.WriteTyped(&buf, conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "a=1.5us\nb=1us\nc=999ns\nd=1ns\ne=0s\n\n" != buf.String() {
+		t.Error(buf.String())
+	}
+
+	conf2, err := dialect.ReadTyped(&buf)
+	if nil != err || !reflect.DeepEqual(conf, conf2) {
+		t.Error(conf2, err)
+	}
+}
+
+func TestMarshalSizeTime(t *testing.T) {
+	type S struct {
+		Cache Size
+		When  time.Time
+	}
+
+	when := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	conf, err := Marshal(&S{Cache: 1 << 30, When: when})
+	if nil != err || Size(1<<30) != conf.Get("Cache") || when != conf.Get("When") {
+		t.Error(conf, err)
+	}
+
+	var s S
+	err = Unmarshal(TypedConfig{"": TypedSection{"Cache": "2GiB", "When": when}}, &s)
+	if nil != err || 2<<30 != s.Cache || !when.Equal(s.When) {
+		t.Error(s, err)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * subtree.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// childName returns the name of the immediate child of prefix that
+// contains the section sect.
+func childName(prefix, sect string) (string, bool) {
+	if "" != prefix {
+		if !strings.HasPrefix(sect, prefix+".") {
+			return "", false
+		}
+		sect = sect[len(prefix)+1:]
+	}
+	if "" == sect {
+		return "", false
+	}
+	if i := strings.IndexByte(sect, '.'); -1 != i {
+		sect = sect[:i]
+	}
+	return sect, true
+}
+
+// subtreeName returns the name of the section sect relative to prefix.
+func subtreeName(prefix, sect string) (string, bool) {
+	switch {
+	case "" == prefix:
+		return sect, true
+	case prefix == sect:
+		return "", true
+	case strings.HasPrefix(sect, prefix+"."):
+		return sect[len(prefix)+1:], true
+	}
+	return "", false
+}
+
+// joinSection returns the name of the section name under prefix.
+func joinSection(prefix, name string) string {
+	switch {
+	case "" == prefix:
+		return name
+	case "" == name:
+		return prefix
+	}
+	return prefix + "." + name
+}
+
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Children lists the names of the immediate child sections of a prefix.
+//
+// Section names form a hierarchy where the components of a name are
+// separated by dots. For example the sections "remote.origin" and
+// "remote.upstream" are children of the (possibly non-existent) section
+// "remote". The prefix "" denotes the root of the hierarchy.
+func (conf Config) Children(prefix string) []string {
+	set := map[string]bool{}
+	for sect := range conf {
+		if name, ok := childName(prefix, sect); ok {
+			set[name] = true
+		}
+	}
+	return sortedNames(set)
+}
+
+// Subtree extracts the sections under a prefix as a new configuration.
+// The section named prefix becomes the unnamed section of the new
+// configuration and the prefix is removed from the names of its children.
+func (conf Config) Subtree(prefix string) Config {
+	sub := Config{}
+	for sect, smap := range conf {
+		if name, ok := subtreeName(prefix, sect); ok {
+			nmap := Section{}
+			for k, v := range smap {
+				nmap[k] = v
+			}
+			sub[name] = nmap
+		}
+	}
+	return sub
+}
+
+// SetSubtree replaces the sections under a prefix with the sections of
+// the supplied configuration. It is the inverse of Subtree.
+func (conf Config) SetSubtree(prefix string, sub Config) {
+	for sect := range conf {
+		if _, ok := subtreeName(prefix, sect); ok {
+			delete(conf, sect)
+		}
+	}
+	for name, smap := range sub {
+		nmap := Section{}
+		for k, v := range smap {
+			nmap[k] = v
+		}
+		conf[joinSection(prefix, name)] = nmap
+	}
+}
+
+// Children lists the names of the immediate child sections of a prefix.
+func (conf TypedConfig) Children(prefix string) []string {
+	set := map[string]bool{}
+	for sect := range conf {
+		if name, ok := childName(prefix, sect); ok {
+			set[name] = true
+		}
+	}
+	return sortedNames(set)
+}
+
+// Subtree extracts the sections under a prefix as a new configuration.
+// The section named prefix becomes the unnamed section of the new
+// configuration and the prefix is removed from the names of its children.
+func (conf TypedConfig) Subtree(prefix string) TypedConfig {
+	sub := TypedConfig{}
+	for sect, smap := range conf {
+		if name, ok := subtreeName(prefix, sect); ok {
+			nmap := TypedSection{}
+			for k, v := range smap {
+				nmap[k] = v
+			}
+			sub[name] = nmap
+		}
+	}
+	return sub
+}
+
+// SetSubtree replaces the sections under a prefix with the sections of
+// the supplied configuration. It is the inverse of Subtree.
+func (conf TypedConfig) SetSubtree(prefix string, sub TypedConfig) {
+	for sect := range conf {
+		if _, ok := subtreeName(prefix, sect); ok {
+			delete(conf, sect)
+		}
+	}
+	for name, smap := range sub {
+		nmap := TypedSection{}
+		for k, v := range smap {
+			nmap[k] = v
+		}
+		conf[joinSection(prefix, name)] = nmap
+	}
+}
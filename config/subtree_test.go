@@ -0,0 +1,128 @@
+/*
+ * subtree_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var subsectionDialect = &Dialect{
+	AssignChars:   "=",
+	CommentChars:  ";#",
+	ReadEmptyKeys: true,
+	Subsections:   true,
+}
+
+func TestSubsections(t *testing.T) {
+	conf, err := subsectionDialect.Read(strings.NewReader(`[core]
+bare=false
+[remote "origin"]
+url=https://example.com/origin
+[remote  "up stream" ]
+url=https://example.com/upstream
+[remote "bad
+`))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "https://example.com/origin" != conf.Get("remote.origin.url") ||
+		"https://example.com/upstream" != conf.Get("remote.up stream.url") ||
+		"false" != conf.Get("core.bare") {
+		t.Error(conf)
+	}
+
+	var buf strings.Builder
+	err = subsectionDialect.Write(&buf, conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "[remote \"origin\"]\n") ||
+		!strings.Contains(buf.String(), "[core]\n") {
+		t.Error(buf.String())
+	}
+	iconf, err := subsectionDialect.Read(strings.NewReader(buf.String()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, iconf) {
+		t.Error(iconf)
+	}
+
+	dialect := *subsectionDialect
+	dialect.Strict = true
+	_, err = dialect.Read(strings.NewReader("[remote \"bad\"\n"))
+	if nil == err {
+		t.Error()
+	}
+}
+
+func TestSubtree(t *testing.T) {
+	conf := Config{}
+	conf.Set("top", "1")
+	conf.Set("remote.k", "v")
+	conf.Set("remote.origin.url", "o")
+	conf.Set("remote.upstream.url", "u")
+	conf.Set("remote.upstream.push.default", "d")
+	conf.Set("remotes.other", "x")
+
+	if !reflect.DeepEqual([]string{"remote", "remotes"}, conf.Children("")) {
+		t.Error(conf.Children(""))
+	}
+	if !reflect.DeepEqual([]string{"origin", "upstream"}, conf.Children("remote")) {
+		t.Error(conf.Children("remote"))
+	}
+	if 0 != len(conf.Children("remote.origin")) {
+		t.Error()
+	}
+
+	sub := conf.Subtree("remote")
+	expected := Config{
+		"":              Section{"k": "v"},
+		"origin":        Section{"url": "o"},
+		"upstream":      Section{"url": "u"},
+		"upstream.push": Section{"default": "d"},
+	}
+	if !reflect.DeepEqual(expected, sub) {
+		t.Error(sub)
+	}
+
+	sub.Set("origin.url", "changed")
+	if "o" != conf.Get("remote.origin.url") {
+		t.Error()
+	}
+	sub.Delete("upstream.push.default")
+	conf.SetSubtree("remote", sub)
+	if "changed" != conf.Get("remote.origin.url") ||
+		"" != conf.Get("remote.upstream.push.default") ||
+		"x" != conf.Get("remotes.other") || "1" != conf.Get("top") ||
+		"v" != conf.Get("remote.k") {
+		t.Error(conf)
+	}
+
+	tconf := TypedConfig{}
+	tconf.Set("a.b.c", int64(1))
+	tconf.Set("a.d.e", true)
+	if !reflect.DeepEqual([]string{"b", "d"}, tconf.Children("a")) {
+		t.Error()
+	}
+	tsub := tconf.Subtree("a.b")
+	if int64(1) != tsub.Get("c") {
+		t.Error()
+	}
+	tconf.SetSubtree("a", TypedConfig{"x": TypedSection{"y": "z"}})
+	if "z" != tconf.Get("a.x.y") || nil != tconf.Get("a.b.c") {
+		t.Error(tconf)
+	}
+}
@@ -0,0 +1,445 @@
+/*
+ * toml.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/billziss-gh/golib/errors"
+)
+
+// tomlParser parses the TOML subset supported by ReadTOML.
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return errors.New(
+		fmt.Sprintf("line %d: %s", p.line, fmt.Sprintf(format, args...)), nil, ErrConfig)
+}
+
+func (p *tomlParser) eof() bool {
+	return len(p.s) <= p.pos
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// skipSpace skips spaces and tabs.
+func (p *tomlParser) skipSpace() {
+	for c := p.peek(); ' ' == c || '\t' == c; c = p.peek() {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to (but not including) the end of line.
+func (p *tomlParser) skipComment() {
+	if '#' == p.peek() {
+		for !p.eof() && '\n' != p.s[p.pos] {
+			p.pos++
+		}
+	}
+}
+
+// skipBlank skips spaces, comments and newlines.
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		switch p.peek() {
+		case '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		default:
+			return
+		}
+	}
+}
+
+// endLine consumes the remainder of a line, which must be blank.
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if '\r' == p.peek() {
+		p.pos++
+	}
+	switch p.peek() {
+	case 0:
+		return nil
+	case '\n':
+		p.pos++
+		p.line++
+		return nil
+	}
+	return p.errorf("unexpected %q", p.peek())
+}
+
+func isBareKeyChar(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		'_' == c || '-' == c
+}
+
+// key parses a dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var parts []string
+	for {
+		p.skipSpace()
+		var part string
+		switch c := p.peek(); {
+		case '"' == c, '\'' == c:
+			s, err := p.str()
+			if nil != err {
+				return nil, err
+			}
+			part = s
+		case isBareKeyChar(c):
+			i := p.pos
+			for isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			part = p.s[i:p.pos]
+		default:
+			return nil, p.errorf("invalid key")
+		}
+		if -1 != strings.IndexByte(part, '.') {
+			return nil, p.errorf("key %q contains a dot", part)
+		}
+		parts = append(parts, part)
+		p.skipSpace()
+		if '.' != p.peek() {
+			return parts, nil
+		}
+		p.pos++
+	}
+}
+
+// str parses a single line basic or literal string.
+func (p *tomlParser) str() (string, error) {
+	q := p.s[p.pos]
+	if strings.HasPrefix(p.s[p.pos:], string([]byte{q, q, q})) {
+		return "", p.errorf("multi-line strings are not supported")
+	}
+	for i := p.pos + 1; len(p.s) > i; i++ {
+		switch c := p.s[i]; {
+		case '\n' == c:
+			return "", p.errorf("unterminated string")
+		case '\\' == c && '"' == q:
+			i++
+		case q == c:
+			raw := p.s[p.pos : i+1]
+			p.pos = i + 1
+			if '\'' == q {
+				return raw[1 : len(raw)-1], nil
+			}
+			s, err := strconv.Unquote(raw)
+			if nil != err {
+				return "", p.errorf("invalid string %s", raw)
+			}
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// value parses a value: a string, number, boolean or array.
+func (p *tomlParser) value() (interface{}, error) {
+	switch c := p.peek(); c {
+	case '"', '\'':
+		return p.str()
+	case '[':
+		p.pos++
+		list := []interface{}{}
+		for {
+			p.skipBlank()
+			if ']' == p.peek() {
+				p.pos++
+				return list, nil
+			}
+			v, err := p.value()
+			if nil != err {
+				return nil, err
+			}
+			list = append(list, v)
+			p.skipBlank()
+			switch p.peek() {
+			case ',':
+				p.pos++
+			case ']':
+			default:
+				return nil, p.errorf("expected , or ] in array")
+			}
+		}
+	case '{':
+		return nil, p.errorf("inline tables are not supported")
+	}
+
+	i := p.pos
+	for c := p.peek(); 0 != c && ' ' != c && '\t' != c && '\r' != c && '\n' != c &&
+		',' != c && ']' != c && '#' != c; c = p.peek() {
+		p.pos++
+	}
+	tok := p.s[i:p.pos]
+	switch tok {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if !tomlNumber(tok) {
+		return nil, p.errorf("invalid value %q", tok)
+	}
+	if v, err := strconv.ParseInt(tok, 0, 64); nil == err {
+		return v, nil
+	}
+	if v, err := strconv.ParseFloat(strings.Replace(tok, "_", "", -1), 64); nil == err &&
+		"" != tok && '.' != tok[0] && '.' != tok[len(tok)-1] {
+		return v, nil
+	}
+	return nil, p.errorf("invalid value %q", tok)
+}
+
+// tomlNumber checks the prefix of a number. TOML does not allow leading
+// zeros in decimal numbers (which strconv reads as octal) or signs before
+// the 0x, 0o and 0b prefixes.
+func tomlNumber(tok string) bool {
+	s := tok
+	if 0 < len(s) && ('+' == s[0] || '-' == s[0]) {
+		s = s[1:]
+	}
+	if 1 < len(s) && '0' == s[0] {
+		switch c := s[1]; {
+		case '0' <= c && c <= '9', '_' == c, 'X' == c, 'O' == c, 'B' == c:
+			return false
+		case 'x' == c, 'o' == c, 'b' == c:
+			return len(s) == len(tok)
+		}
+	}
+	return true
+}
+
+// ReadTOML reads a typed configuration from a document in a TOML
+// compatible format.
+//
+// The supported subset consists of tables, key/value pairs (including
+// dotted keys), single line basic and literal strings, integers, floats,
+// booleans and arrays of these values. Tables map to sections whose names
+// are the dotted table names; strings, integers and floats are read as
+// string, int64 and float64 respectively. Arrays of tables, inline tables,
+// multi-line strings and date-times are not supported.
+func ReadTOML(reader io.Reader) (TypedConfig, error) {
+	buf, err := ioutil.ReadAll(reader)
+	if nil != err {
+		return nil, err
+	}
+
+	p := &tomlParser{s: string(buf), line: 1}
+	conf := TypedConfig{}
+	sect := ""
+	for {
+		p.skipBlank()
+		if p.eof() {
+			break
+		}
+
+		if '[' == p.peek() {
+			p.pos++
+			if '[' == p.peek() {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			parts, err := p.key()
+			if nil != err {
+				return nil, err
+			}
+			if ']' != p.peek() {
+				return nil, p.errorf("expected ] after table name")
+			}
+			p.pos++
+			sect = strings.Join(parts, ".")
+			if _, ok := conf[sect]; !ok {
+				conf[sect] = TypedSection{}
+			}
+		} else {
+			parts, err := p.key()
+			if nil != err {
+				return nil, err
+			}
+			if '=' != p.peek() {
+				return nil, p.errorf("expected = after key")
+			}
+			p.pos++
+			p.skipSpace()
+			valu, err := p.value()
+			if nil != err {
+				return nil, err
+			}
+			s := joinSection(sect, strings.Join(parts[:len(parts)-1], "."))
+			name := parts[len(parts)-1]
+			if _, ok := conf[s][name]; ok {
+				return nil, p.errorf("duplicate key %s", propertyName(s, name))
+			}
+			if nil == conf[s] {
+				conf[s] = TypedSection{}
+			}
+			conf[s][name] = valu
+		}
+
+		err = p.endLine()
+		if nil != err {
+			return nil, err
+		}
+	}
+
+	return conf, nil
+}
+
+// WriteTOML writes a typed configuration in a TOML compatible format.
+// It is the inverse of ReadTOML. Properties of the unnamed section are
+// written first, followed by one table per section. Values other than
+// strings, numbers, booleans and lists are written as strings.
+func WriteTOML(writer io.Writer, conf TypedConfig) error {
+	bufw := bufio.NewWriter(writer)
+
+	sects := make([]string, 0, len(conf))
+	for sect := range conf {
+		if "" != sect {
+			sects = append(sects, sect)
+		}
+	}
+	sort.Strings(sects)
+	if _, ok := conf[""]; ok {
+		sects = append([]string{""}, sects...)
+	}
+
+	for i, sect := range sects {
+		if "" != sect {
+			if 0 < i {
+				bufw.WriteByte('\n')
+			}
+			parts := strings.Split(sect, ".")
+			for j, part := range parts {
+				parts[j] = tomlKey(part)
+			}
+			bufw.WriteString("[" + strings.Join(parts, ".") + "]\n")
+		}
+
+		tsmap := conf[sect]
+		names := make([]string, 0, len(tsmap))
+		for name := range tsmap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			bufw.WriteString(tomlKey(name))
+			bufw.WriteString(" = ")
+			bufw.WriteString(tomlValue(tsmap[name]))
+			bufw.WriteByte('\n')
+		}
+	}
+
+	return bufw.Flush()
+}
+
+func tomlKey(s string) string {
+	for i := 0; len(s) > i; i++ {
+		if !isBareKeyChar(s[i]) {
+			return tomlString(s)
+		}
+	}
+	if "" == s {
+		return `""`
+	}
+	return s
+}
+
+func tomlString(s string) string {
+	buf := bytes.Buffer{}
+	buf.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(c)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if 0x20 > c || 0x7f == c {
+				fmt.Fprintf(&buf, `\u%04x`, c)
+			} else {
+				buf.WriteRune(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func tomlValue(valu interface{}) string {
+	if list, ok := listValue(valu); ok {
+		parts := make([]string, len(list))
+		for i, elem := range list {
+			parts[i] = tomlValue(elem)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	switch v := valu.(type) {
+	case string:
+		return tomlString(v)
+	case bool,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return formatTyped(v)
+	case float32:
+		return tomlValue(float64(v))
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "nan"
+		case math.IsInf(v, 1):
+			return "inf"
+		case math.IsInf(v, -1):
+			return "-inf"
+		}
+		return formatFloat(v, 64)
+	default:
+		return tomlString(formatTyped(v))
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * toml_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadTOML(t *testing.T) {
+	conf, err := ReadTOML(strings.NewReader(`# comment
+name = "app"
+path = 'C:\temp'
+count = 1_000
+mask = 0xff
+mode = 0o755
+bits = 0b101
+zero = -0
+ratio = 1.0
+debug = true
+tags = [
+    "a", # first
+    1,
+]
+server.port = 8080
+
+[remote.origin]
+url = "https://example.com/\"repo\""
+"fetch spec" = ['+refs/*', "x"] # trailing
+
+["up stream"]
+`))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	expected := TypedConfig{
+		"": TypedSection{
+			"name":  "app",
+			"path":  `C:\temp`,
+			"count": int64(1000),
+			"mask":  int64(255),
+			"mode":  int64(493),
+			"bits":  int64(5),
+			"zero":  int64(0),
+			"ratio": 1.0,
+			"debug": true,
+			"tags":  []interface{}{"a", int64(1)},
+		},
+		"server": TypedSection{"port": int64(8080)},
+		"remote.origin": TypedSection{
+			"url":        `https://example.com/"repo"`,
+			"fetch spec": []interface{}{"+refs/*", "x"},
+		},
+		"up stream": TypedSection{},
+	}
+	if !reflect.DeepEqual(expected, conf) {
+		t.Error(conf)
+	}
+
+	for _, s := range []string{
+		"a = 1\na = 2\n",
+		"a = {b = 1}\n",
+		"[[a]]\n",
+		"a = \"b\n",
+		"a = 1 2\n",
+		"a = bogus\n",
+		"[a\n",
+		"a = 0755\n",
+		"a = 00.5\n",
+		"a = 0_1\n",
+		"a = +0x10\n",
+		"a = 0X10\n",
+	} {
+		if _, err := ReadTOML(strings.NewReader(s)); nil == err {
+			t.Error(s)
+		}
+	}
+}
+
+func TestWriteTOML(t *testing.T) {
+	conf := TypedConfig{
+		"": TypedSection{
+			"name":  "app\t\"x\"",
+			"ratio": 2.0,
+		},
+		"remote.origin": TypedSection{
+			"url":        "https://example.com",
+			"fetch spec": []interface{}{"a", int64(1), true},
+		},
+		"up stream": TypedSection{},
+	}
+
+	buf := bytes.Buffer{}
+	err := WriteTOML(&buf, conf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	expected := `name = "app\t\"x\""
+ratio = 2.0
+
+[remote.origin]
+"fetch spec" = ["a", 1, true]
+url = "https://example.com"
+
+["up stream"]
+`
+	if expected != buf.String() {
+		t.Error(buf.String())
+	}
+
+	conf2, err := ReadTOML(&buf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf, conf2) {
+		t.Error(conf2)
+	}
+}
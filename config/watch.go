@@ -0,0 +1,195 @@
+/*
+ * watch.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Changes describes the differences between two configurations.
+// Properties are named using the syntax SECTION.PROPNAME.
+type Changes struct {
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+// Empty determines whether there are no differences.
+func (changes *Changes) Empty() bool {
+	return 0 == len(changes.Added) && 0 == len(changes.Changed) && 0 == len(changes.Removed)
+}
+
+// Diff computes the differences between two configurations.
+func Diff(oldconf, newconf Config) Changes {
+	var changes Changes
+	for sect, smap := range newconf {
+		for name, valu := range smap {
+			if v, ok := oldconf[sect][name]; !ok {
+				changes.Added = append(changes.Added, propertyName(sect, name))
+			} else if v != valu {
+				changes.Changed = append(changes.Changed, propertyName(sect, name))
+			}
+		}
+	}
+	for sect, smap := range oldconf {
+		for name := range smap {
+			if _, ok := newconf[sect][name]; !ok {
+				changes.Removed = append(changes.Removed, propertyName(sect, name))
+			}
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// PollInterval is the interval used to poll configuration files for
+// changes when file system notifications are not available.
+var PollInterval = 2 * time.Second
+
+// newNotifier starts watching a file for changes and calls notify when
+// the file may have changed. It returns a function that stops watching.
+var newNotifier = newPollNotifier
+
+func newPollNotifier(path string, notify func()) (func(), error) {
+	stat := func() os.FileInfo {
+		info, _ := os.Stat(path)
+		return info
+	}
+
+	done := make(chan struct{})
+	last := stat()
+	go func() {
+		ticker := time.NewTicker(PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info := stat()
+				if nil == last || nil == info {
+					if last != info {
+						notify()
+					}
+				} else if !os.SameFile(last, info) ||
+					last.ModTime() != info.ModTime() || last.Size() != info.Size() {
+					notify()
+				}
+				last = info
+			}
+		}
+	}()
+
+	return func() { close(done) }, nil
+}
+
+// Watcher watches a configuration file and reloads it when it changes.
+//
+// On Linux the Watcher uses inotify and only reloads the file after it has
+// been closed or atomically renamed into place (as done by util.WriteData),
+// so that partial writes are ignored. On other systems, or when inotify is
+// not available, the file is polled every PollInterval.
+type Watcher struct {
+	dialect *Dialect
+	path    string
+	conf    Config
+	subs    []func(conf Config, changes Changes, err error)
+	stop    func()
+	mux     sync.Mutex
+	rmux    sync.Mutex
+}
+
+// Watch reads a configuration file and starts watching it for changes.
+func (dialect *Dialect) Watch(path string) (*Watcher, error) {
+	conf, err := dialect.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+
+	watcher := &Watcher{dialect: dialect, path: path, conf: conf}
+	watcher.stop, err = newNotifier(path, watcher.reload)
+	if nil != err {
+		return nil, err
+	}
+
+	return watcher, nil
+}
+
+// Config returns the current configuration.
+func (self *Watcher) Config() Config {
+	self.mux.Lock()
+	defer self.mux.Unlock()
+	return self.conf
+}
+
+// Subscribe adds a function to be called when the configuration changes.
+// The function receives the new configuration and its differences from
+// the previous one. If the file cannot be reloaded the function receives
+// the current configuration, no changes and the error.
+func (self *Watcher) Subscribe(fn func(conf Config, changes Changes, err error)) {
+	self.mux.Lock()
+	defer self.mux.Unlock()
+	self.subs = append(self.subs, fn)
+}
+
+// Close stops watching the configuration file.
+func (self *Watcher) Close() error {
+	self.mux.Lock()
+	stop := self.stop
+	self.stop = nil
+	self.mux.Unlock()
+
+	if nil != stop {
+		stop()
+	}
+	return nil
+}
+
+func (self *Watcher) reload() {
+	self.rmux.Lock()
+	defer self.rmux.Unlock()
+
+	newconf, err := self.dialect.ReadFile(self.path)
+
+	self.mux.Lock()
+	oldconf := self.conf
+	var changes Changes
+	if nil == err {
+		changes = Diff(oldconf, newconf)
+		if changes.Empty() {
+			self.mux.Unlock()
+			return
+		}
+		self.conf = newconf
+	} else {
+		newconf = oldconf
+	}
+	subs := make([]func(Config, Changes, error), len(self.subs))
+	copy(subs, self.subs)
+	self.mux.Unlock()
+
+	for _, fn := range subs {
+		fn(newconf, changes, err)
+	}
+}
+
+// Watch reads a configuration file and starts watching it for changes
+// using the default dialect.
+func Watch(path string) (*Watcher, error) {
+	return DefaultDialect.Watch(path)
+}
@@ -0,0 +1,77 @@
+/*
+ * watch_linux.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+func init() {
+	newNotifier = newInotifyNotifier
+}
+
+// newInotifyNotifier watches the directory of a file, because atomic
+// renames replace the file (and its inode). Only IN_CLOSE_WRITE and
+// IN_MOVED_TO events are reported; this ignores partial writes.
+func newInotifyNotifier(path string, notify func()) (func(), error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if nil != err {
+		return newPollNotifier(path, notify)
+	}
+
+	_, err = syscall.InotifyAddWatch(fd, filepath.Dir(path),
+		syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO)
+	if nil != err {
+		syscall.Close(fd)
+		return newPollNotifier(path, notify)
+	}
+
+	// a non-blocking file is added to the runtime poller;
+	// closing it unblocks a pending Read
+	file := os.NewFile(uintptr(fd), "inotify")
+	name := filepath.Base(path)
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := file.Read(buf)
+			if nil != err {
+				return
+			}
+
+			changed := false
+			for off := 0; n >= off+syscall.SizeofInotifyEvent; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				off += syscall.SizeofInotifyEvent
+				end := off + int(event.Len)
+				if end > n {
+					break
+				}
+				if 0 != event.Mask&syscall.IN_Q_OVERFLOW ||
+					name == string(bytes.TrimRight(buf[off:end], "\x00")) {
+					changed = true
+				}
+				off = end
+			}
+
+			if changed {
+				notify()
+			}
+		}
+	}()
+
+	return func() { file.Close() }, nil
+}
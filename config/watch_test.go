@@ -0,0 +1,105 @@
+/*
+ * watch_test.go
+ *
+ * Copyright 2018-2021 Bill Zissimopoulos
+ */
+/*
+ * This file is part of golib.
+ *
+ * It is licensed under the MIT license. The full license text can be found
+ * in the License.txt file at the root of this project.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/billziss-gh/golib/util"
+)
+
+func TestDiff(t *testing.T) {
+	oldconf := Config{"": Section{"a": "1", "b": "2"}, "s": Section{"c": "3"}}
+	newconf := Config{"": Section{"a": "1", "b": "20"}, "t": Section{"d": "4"}}
+
+	changes := Diff(oldconf, newconf)
+	expected := Changes{
+		Added:   []string{"t.d"},
+		Changed: []string{"b"},
+		Removed: []string{"s.c"},
+	}
+	if !reflect.DeepEqual(expected, changes) {
+		t.Error(changes)
+	}
+
+	changes = Diff(oldconf, oldconf)
+	if !changes.Empty() {
+		t.Error()
+	}
+}
+
+func testWatch(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"watch.conf": "a=1\nb=2\n",
+	})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "watch.conf")
+
+	watcher, err := Watch(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	if "1" != watcher.Config().Get("a") {
+		t.Error()
+	}
+
+	ch := make(chan Changes, 10)
+	watcher.Subscribe(func(conf Config, changes Changes, err error) {
+		if nil == err {
+			ch <- changes
+		}
+	})
+
+	err = util.WriteData(path, 0644, []byte("a=10\nc=3\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	select {
+	case changes := <-ch:
+		expected := Changes{
+			Added:   []string{"c"},
+			Changed: []string{"a"},
+			Removed: []string{"b"},
+		}
+		if !reflect.DeepEqual(expected, changes) {
+			t.Error(changes)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	if "10" != watcher.Config().Get("a") {
+		t.Error()
+	}
+}
+
+func TestWatch(t *testing.T) {
+	testWatch(t)
+}
+
+func TestWatchPoll(t *testing.T) {
+	savedNotifier, savedInterval := newNotifier, PollInterval
+	defer func() {
+		newNotifier, PollInterval = savedNotifier, savedInterval
+	}()
+	newNotifier, PollInterval = newPollNotifier, 10*time.Millisecond
+
+	testWatch(t)
+}
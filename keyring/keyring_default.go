@@ -15,6 +15,10 @@
 // Windows, Secret Service on Linux).
 package keyring
 
+import (
+	"crypto/sha256"
+)
+
 const ErrKeyring = "ErrKeyring"
 
 // Keyring is the interface that a system-specific or custom keyring must
@@ -47,3 +51,17 @@ func Set(service, user, pass string) error {
 func Delete(service, user string) error {
 	return DefaultKeyring.Delete(service, user)
 }
+
+// KeyFunc returns a function that gets a 32-byte encryption key from the
+// default keyring; the key is the SHA-256 hash of the password for service
+// and user. It is intended for use with config.Dialect.Key.
+func KeyFunc(service, user string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		pass, err := Get(service, user)
+		if nil != err {
+			return nil, err
+		}
+		key := sha256.Sum256([]byte(pass))
+		return key[:], nil
+	}
+}
@@ -182,3 +182,31 @@ func TestOverlayKeyring(t *testing.T) {
 
 	testKeyringInstance(t, &OverlayKeyring{Keyrings: []Keyring{ring1, ring2}})
 }
+
+func TestKeyFunc(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "keyring_test")
+	os.Remove(path)
+	defer os.Remove(path)
+
+	saved := DefaultKeyring
+	defer func() {
+		DefaultKeyring = saved
+	}()
+	DefaultKeyring = &FileKeyring{Path: path}
+
+	keyfn := KeyFunc("keyring", "TestKeyFunc")
+	_, err := keyfn()
+	if nil == err {
+		t.Error()
+	}
+
+	err = Set("keyring", "TestKeyFunc", "hello")
+	if nil != err {
+		t.Error(err)
+	}
+
+	key, err := keyfn()
+	if 32 != len(key) || nil != err {
+		t.Error(err)
+	}
+}
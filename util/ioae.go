@@ -19,31 +19,59 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+
+	"github.com/billziss-gh/golib/errors"
 )
 
-func ReadAeData(path string, key []byte) (data []byte, err error) {
-	idata, err := ReadFunc(path, func(file *os.File) (interface{}, error) {
-		c, err := aes.NewCipher(key)
-		if nil != err {
-			return nil, err
-		}
+func newAead(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if nil != err {
+		return nil, err
+	}
 
-		ae, err := cipher.NewGCM(c)
-		if nil != err {
-			return nil, err
-		}
+	return cipher.NewGCM(c)
+}
+
+// SealAeData encrypts and authenticates data using AES-GCM with the
+// supplied key. The returned ciphertext is prefixed with a random nonce.
+func SealAeData(data []byte, key []byte) ([]byte, error) {
+	ae, err := newAead(key)
+	if nil != err {
+		return nil, err
+	}
+
+	nonce := make([]byte, ae.NonceSize(), ae.NonceSize()+len(data)+ae.Overhead())
+	_, err = rand.Read(nonce)
+	if nil != err {
+		return nil, err
+	}
+
+	return ae.Seal(nonce, nonce, data, nil), nil
+}
+
+// OpenAeData decrypts and authenticates data produced by SealAeData.
+func OpenAeData(data []byte, key []byte) ([]byte, error) {
+	ae, err := newAead(key)
+	if nil != err {
+		return nil, err
+	}
 
+	n := ae.NonceSize()
+	if len(data) < n {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	return ae.Open(nil, data[:n], data[n:], nil)
+}
+
+func ReadAeData(path string, key []byte) (data []byte, err error) {
+	idata, err := ReadFunc(path, func(file *os.File) (interface{}, error) {
 		data, err := ioutil.ReadAll(file)
 		if nil != err {
 			return nil, err
 		}
 
-		var nonce []byte
-		if n := ae.NonceSize(); len(data) > n {
-			nonce, data = data[:n], data[n:]
-		}
-
-		return ae.Open(data[:0], nonce, data, nil)
+		return OpenAeData(data, key)
 	})
 
 	if nil == err {
@@ -55,30 +83,12 @@ func ReadAeData(path string, key []byte) (data []byte, err error) {
 
 func WriteAeData(path string, perm os.FileMode, data []byte, key []byte) (err error) {
 	return WriteFunc(path, perm, func(file *os.File) error {
-		c, err := aes.NewCipher(key)
+		data, err := SealAeData(data, key)
 		if nil != err {
 			return err
 		}
 
-		ae, err := cipher.NewGCM(c)
-		if nil != err {
-			return err
-		}
-
-		nonce := make([]byte, ae.NonceSize())
-		_, err = rand.Read(nonce)
-		if nil != err {
-			return err
-		}
-
-		data = ae.Seal(nil, nonce, data, nil)
-
-		n, err := file.Write(nonce)
-		if nil == err && n < len(nonce) {
-			return io.ErrShortWrite
-		}
-
-		n, err = file.Write(data)
+		n, err := file.Write(data)
 		if nil == err && n < len(data) {
 			err = io.ErrShortWrite
 		}
@@ -40,3 +40,33 @@ func TestReadWriteAeData(t *testing.T) {
 		t.Error()
 	}
 }
+
+func TestSealOpenAeData(t *testing.T) {
+	key := []byte("passpasspasspass")
+
+	s := "hello, encrypted world"
+	data, err := SealAeData(([]byte)(s), key)
+	if nil != err {
+		t.Error(err)
+	}
+
+	b, err := OpenAeData(data, key)
+	if nil != err {
+		t.Error(err)
+	}
+
+	if s != string(b) {
+		t.Error()
+	}
+
+	data[len(data)-1] ^= 1
+	_, err = OpenAeData(data, key)
+	if nil == err {
+		t.Error()
+	}
+
+	_, err = OpenAeData(data[:4], key)
+	if nil == err {
+		t.Error()
+	}
+}